@@ -0,0 +1,306 @@
+// Package config reads and writes the versioned app configuration file.
+// The file is JSON, stored under $XDG_CONFIG_HOME/gist-editor. Files written by
+// older versions of the app are migrated to the current schema when loaded.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+	"github.com/fieldse/gist-editor/internal/tokensource"
+)
+
+// Name of the app's config directory
+const AppDirName = "gist-editor"
+
+// Name of the config file within the config directory
+const FileName = "config.json"
+
+// CurrentVersion is the current config schema version
+const CurrentVersion = 5
+
+// Config is the app configuration
+type Config struct {
+	Version       int         `json:"version"`
+	Accounts      []Account   `json:"accounts"`
+	ActiveAccount string      `json:"active_account"` // name of the account in use
+	TokenSources  []string    `json:"token_sources"`  // where to look for the Github token, in order: store, env, gh
+	Preferences   Preferences `json:"preferences"`
+}
+
+// Account holds the Github host settings of a named account. Empty URLs use github.com.
+// The token is kept in the secret store, not in the config file.
+type Account struct {
+	Name          string `json:"name"`
+	APIBaseURL    string `json:"api_base_url,omitempty"`
+	WebBaseURL    string `json:"web_base_url,omitempty"`
+	OAuthClientID string `json:"oauth_client_id,omitempty"`
+}
+
+// Default returns the default configuration, with a single github.com account
+func Default() Config {
+	return Config{
+		Version:       CurrentVersion,
+		Accounts:      []Account{{Name: github.DefaultAccountName}},
+		ActiveAccount: github.DefaultAccountName,
+		TokenSources:  append([]string{}, tokensource.DefaultOrder...),
+		Preferences:   DefaultPreferences(),
+	}
+}
+
+// GithubAccounts returns the accounts as github.Accounts, without tokens
+func (c Config) GithubAccounts() github.Accounts {
+	res := github.Accounts{Active: c.ActiveAccount}
+	for _, a := range c.Accounts {
+		res.List = append(res.List, github.GithubConfig{
+			Name:          a.Name,
+			APIBaseURL:    a.APIBaseURL,
+			WebBaseURL:    a.WebBaseURL,
+			OAuthClientID: a.OAuthClientID,
+		})
+	}
+	return res
+}
+
+// SetGithubAccounts stores the host settings of the accounts. Tokens are not stored.
+func (c *Config) SetGithubAccounts(accounts github.Accounts) {
+	c.Accounts = nil
+	for _, g := range accounts.List {
+		c.Accounts = append(c.Accounts, Account{Name: g.Name, APIBaseURL: g.APIBaseURL, WebBaseURL: g.WebBaseURL, OAuthClientID: g.OAuthClientID})
+	}
+	c.ActiveAccount = accounts.Active
+}
+
+// GithubConfig returns the host settings of the active account as a github.GithubConfig, without a token
+func (c Config) GithubConfig() github.GithubConfig {
+	accounts := c.GithubAccounts()
+	if a := accounts.ActiveAccount(); a != nil {
+		return *a
+	}
+	return github.GithubConfig{Name: github.DefaultAccountName}
+}
+
+// SetGithubConfig stores the host settings of the account named in g, adding the account if
+// it doesn't exist. The token is not stored.
+func (c *Config) SetGithubConfig(g github.GithubConfig) {
+	accounts := c.GithubAccounts()
+	if a := accounts.Get(g.Name); a != nil {
+		*a = g
+	} else {
+		accounts.List = append(accounts.List, g)
+	}
+	c.SetGithubAccounts(accounts)
+}
+
+// ValidationError describes an invalid config setting
+type ValidationError struct {
+	Field string // the JSON path of the setting, eg: accounts[0].api_base_url
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid setting %s: %s", e.Field, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// Validate checks the config settings, and returns a *ValidationError for the first invalid setting
+func (c Config) Validate() error {
+	if c.Version != CurrentVersion {
+		return &ValidationError{Field: "version", Err: fmt.Errorf("unsupported version %d, expected %d", c.Version, CurrentVersion)}
+	}
+	if len(c.Accounts) == 0 {
+		return &ValidationError{Field: "accounts", Err: fmt.Errorf("at least one account is required")}
+	}
+	names := map[string]bool{}
+	for i, a := range c.Accounts {
+		prefix := fmt.Sprintf("accounts[%d].", i)
+		if err := github.ValidAccountName(a.Name); err != nil {
+			return &ValidationError{Field: prefix + "name", Err: err}
+		}
+		if names[a.Name] {
+			return &ValidationError{Field: prefix + "name", Err: fmt.Errorf("duplicate account name %s", a.Name)}
+		}
+		names[a.Name] = true
+		if err := github.ValidAPIBaseURL(a.APIBaseURL); err != nil {
+			return &ValidationError{Field: prefix + "api_base_url", Err: err}
+		}
+		if err := github.ValidWebBaseURL(a.WebBaseURL); err != nil {
+			return &ValidationError{Field: prefix + "web_base_url", Err: err}
+		}
+	}
+	if !names[c.ActiveAccount] {
+		return &ValidationError{Field: "active_account", Err: fmt.Errorf("no account named %q", c.ActiveAccount)}
+	}
+	if len(c.TokenSources) == 0 {
+		return &ValidationError{Field: "token_sources", Err: fmt.Errorf("at least one token source is required")}
+	}
+	sources := map[string]bool{}
+	for i, src := range c.TokenSources {
+		field := fmt.Sprintf("token_sources[%d]", i)
+		if err := tokensource.Valid(src); err != nil {
+			return &ValidationError{Field: field, Err: err}
+		}
+		if sources[src] {
+			return &ValidationError{Field: field, Err: fmt.Errorf("duplicate token source %s", src)}
+		}
+		sources[src] = true
+	}
+	return c.Preferences.Validate()
+}
+
+// Dir returns the app config directory: $XDG_CONFIG_HOME/gist-editor, or the
+// platform's user config directory if XDG_CONFIG_HOME is not set.
+func Dir() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		var err error
+		base, err = os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("find user config dir: %w", err)
+		}
+	}
+	return filepath.Join(base, AppDirName), nil
+}
+
+// LegacyDir returns the config directory used by earlier versions of the app,
+// ~/gist-editor, or an empty string if the home directory is unknown
+func LegacyDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, AppDirName)
+}
+
+// Load reads the config file from dir, migrating it to the current version if needed.
+// If there is no config file, the Github host file of earlier versions is imported
+// from legacyDir, if given; otherwise the default config is returned.
+func Load(dir string, legacyDir string) (Config, error) {
+	fp := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(fp)
+	if errors.Is(err, os.ErrNotExist) {
+		return importLegacy(dir, legacyDir)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("read config file: %w", err)
+	}
+	c, migrated, err := parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("config file %s: %w", fp, err)
+	}
+	if migrated {
+		if err := Save(dir, c); err != nil {
+			return Config{}, err
+		}
+		logger.Info("migrated config file %s to version %d", fp, c.Version)
+	}
+	return c, nil
+}
+
+// Save validates the config, and writes it to the config file in dir
+func Save(dir string, c Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	fp := filepath.Join(dir, FileName)
+	tmp := fp + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := os.Rename(tmp, fp); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	logger.Debug("saved config file %s", fp)
+	return nil
+}
+
+// parse decodes a config file, migrating it to the current version.
+// Returns true if the file was migrated.
+func parse(data []byte) (Config, bool, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Config{}, false, fmt.Errorf("invalid JSON: %w", err)
+	}
+	version, err := rawVersion(raw)
+	if err != nil {
+		return Config{}, false, err
+	}
+	if version > CurrentVersion {
+		return Config{}, false, fmt.Errorf("version %d was written by a newer version of the app", version)
+	}
+	migrated := version < CurrentVersion
+	for ; version < CurrentVersion; version++ {
+		if err := migrations[version](raw); err != nil {
+			return Config{}, false, fmt.Errorf("migrate from version %d: %w", version, err)
+		}
+		raw["version"] = version + 1
+	}
+
+	// Decode the migrated document, rejecting unknown settings
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return Config{}, false, err
+	}
+	var c Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Config{}, false, fmt.Errorf("invalid settings: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, false, err
+	}
+	return c, migrated, nil
+}
+
+// rawVersion returns the version of an undecoded config file. Files without a version are version 0.
+func rawVersion(raw map[string]interface{}) (int, error) {
+	v, ok := raw["version"]
+	if !ok {
+		return 0, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f != float64(int(f)) || f < 0 {
+		return 0, &ValidationError{Field: "version", Err: fmt.Errorf("must be a whole number, got %v", v)}
+	}
+	return int(f), nil
+}
+
+// importLegacy imports the Github host file of earlier versions, as a version 0 config,
+// and saves it as the config file. Returns the default config if there is none.
+func importLegacy(dir string, legacyDir string) (Config, error) {
+	if legacyDir == "" {
+		return Default(), nil
+	}
+	fp := filepath.Join(legacyDir, legacyHostFile)
+	data, err := os.ReadFile(fp)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("read legacy config file: %w", err)
+	}
+	c, _, err := parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("legacy config file %s: %w", fp, err)
+	}
+	if err := Save(dir, c); err != nil {
+		return Config{}, err
+	}
+	logger.Info("imported legacy config file %s", fp)
+	return c, nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Default(t *testing.T) {
+	c, err := Load(t.TempDir(), t.TempDir())
+	require.Nil(t, err)
+	assert.Equal(t, Default(), c)
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	c := Default()
+	c.Accounts[0].APIBaseURL = "https://github.example.com/api/v3"
+	require.Nil(t, Save(dir, c))
+
+	loaded, err := Load(dir, "")
+	require.Nil(t, err)
+	assert.Equal(t, c, loaded)
+	assert.Equal(t, "https://github.example.com", loaded.GithubConfig().WebURL())
+}
+
+func TestLoad_MigratesLegacyHostFile(t *testing.T) {
+	dir, legacy := t.TempDir(), t.TempDir()
+	data := `{"api_base_url": "https://github.example.com/api/v3", "web_base_url": ""}`
+	require.Nil(t, os.WriteFile(filepath.Join(legacy, "github-host.json"), []byte(data), 0644))
+
+	c, err := Load(dir, legacy)
+	require.Nil(t, err)
+	assert.Equal(t, CurrentVersion, c.Version)
+	assert.Equal(t, "https://github.example.com/api/v3", c.GithubConfig().APIBaseURL)
+	assert.FileExists(t, filepath.Join(dir, FileName), "the migrated config should be saved")
+}
+
+func TestLoad_MigratesV1(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"version": 1, "github": {"api_base_url": "https://github.example.com/api/v3"}}`
+	require.Nil(t, os.WriteFile(filepath.Join(dir, FileName), []byte(data), 0644))
+
+	c, err := Load(dir, "")
+	require.Nil(t, err)
+	require.Len(t, c.Accounts, 1)
+	assert.Equal(t, Account{Name: "default", APIBaseURL: "https://github.example.com/api/v3"}, c.Accounts[0])
+	assert.Equal(t, "default", c.ActiveAccount)
+	assert.Equal(t, []string{"store", "env", "gh"}, c.TokenSources)
+	assert.Equal(t, DefaultPreferences(), c.Preferences)
+}
+
+func TestAccounts(t *testing.T) {
+	c := Default()
+	work := github.EnterpriseConfig("github.example.com")
+	work.Name = "work"
+	c.SetGithubConfig(work)
+	require.Nil(t, c.Validate())
+	assert.Equal(t, "default", c.GithubConfig().Name)
+
+	c.ActiveAccount = "work"
+	assert.Equal(t, work, c.GithubConfig())
+	work.WebBaseURL = ""
+	c.SetGithubConfig(work)
+	assert.Len(t, c.Accounts, 2, "existing accounts should be updated")
+
+	c.Accounts = append(c.Accounts, Account{Name: "work"})
+	assert.NotNil(t, c.Validate(), "account names should be unique")
+	c.Accounts = c.Accounts[:2]
+	c.ActiveAccount = "missing"
+	assert.NotNil(t, c.Validate(), "the active account should exist")
+}
+
+func TestLoad_Errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"invalid JSON":     `{"version": 4,`,
+		"newer version":    `{"version": 99}`,
+		"bad version":      `{"version": "one"}`,
+		"unknown setting":  `{"version": 1, "colour": "blue"}`,
+		"invalid host URL": `{"version": 1, "github": {"api_base_url": "github.example.com"}}`,
+		"no accounts":      `{"version": 3, "accounts": [], "active_account": "", "token_sources": ["env"]}`,
+		"bad account name": `{"version": 3, "accounts": [{"name": "../a"}], "active_account": "../a", "token_sources": ["env"]}`,
+		"bad token source": `{"version": 3, "accounts": [{"name": "a"}], "active_account": "a", "token_sources": ["netrc"]}`,
+		"bad preference":   `{"version": 4, "accounts": [{"name": "a"}], "active_account": "a", "token_sources": ["env"], "preferences": {}}`,
+		"bad preferences":  `{"version": 4, "accounts": [{"name": "a"}], "active_account": "a", "token_sources": ["env"], "preferences": "none"}`,
+	} {
+		dir := t.TempDir()
+		require.Nil(t, os.WriteFile(filepath.Join(dir, FileName), []byte(data), 0644))
+		_, err := Load(dir, "")
+		assert.NotNil(t, err, name)
+	}
+
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, FileName), []byte(`{"version": 1, "github": {"web_base_url": "ftp://x"}}`), 0644))
+	_, err := Load(dir, "")
+	var vErr *ValidationError
+	require.ErrorAs(t, err, &vErr)
+	assert.Equal(t, "accounts[0].web_base_url", vErr.Field)
+	assert.Contains(t, vErr.Error(), "web URL", "the error should name the invalid setting")
+}
+
+func TestPreferences(t *testing.T) {
+	p := DefaultPreferences()
+	require.Nil(t, p.Validate())
+
+	size, err := ParseSize(" 1024 x 768 ")
+	require.Nil(t, err)
+	assert.Equal(t, Size{1024, 768}, size)
+	for _, s := range []string{"1024", "axb", "100x100"} {
+		_, err := ParseSize(s)
+		assert.NotNil(t, err, s)
+	}
+
+	p.WrapMode = "soft"
+	assert.NotNil(t, p.Validate())
+	p = DefaultPreferences()
+	p.NewGistFilename = "notes/todo.md"
+	assert.NotNil(t, p.Validate())
+	p = DefaultPreferences()
+	p.WindowSizes.Editor = Size{100, 100}
+	var vErr *ValidationError
+	require.ErrorAs(t, p.Validate(), &vErr)
+	assert.Equal(t, "preferences.window_sizes.editor", vErr.Field)
+}
+
+func TestDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	dir, err := Dir()
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Join("/tmp/xdg", AppDirName), dir)
+}
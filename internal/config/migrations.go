@@ -0,0 +1,101 @@
+// Config schema migrations
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/tokensource"
+)
+
+// The Github host file of earlier versions, which is the version 0 schema
+const legacyHostFile = "github-host.json"
+
+// migrations upgrade an undecoded config document from the version at their
+// index to the next version. Add a migration here when changing the schema,
+// and increment CurrentVersion.
+var migrations = []func(raw map[string]interface{}) error{
+	migrateV0,
+	migrateV1,
+	migrateV2,
+	migrateV3,
+	migrateV4,
+}
+
+// migrateV0 moves the flat Github host settings of github-host.json under "github"
+func migrateV0(raw map[string]interface{}) error {
+	gh := map[string]interface{}{}
+	for _, key := range []string{"api_base_url", "web_base_url", "oauth_client_id"} {
+		v, ok := raw[key]
+		if !ok {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return &ValidationError{Field: key, Err: fmt.Errorf("must be a string, got %v", v)}
+		}
+		if s != "" {
+			gh[key] = s
+		}
+		delete(raw, key)
+	}
+	raw["github"] = gh
+	return nil
+}
+
+// migrateV1 moves the Github host settings under "github" into a list of named
+// accounts, as the single active account
+func migrateV1(raw map[string]interface{}) error {
+	account := map[string]interface{}{}
+	if v, ok := raw["github"]; ok {
+		gh, ok := v.(map[string]interface{})
+		if !ok {
+			return &ValidationError{Field: "github", Err: fmt.Errorf("must be an object, got %v", v)}
+		}
+		account = gh
+		delete(raw, "github")
+	}
+	account["name"] = github.DefaultAccountName
+	raw["accounts"] = []interface{}{account}
+	raw["active_account"] = github.DefaultAccountName
+	return nil
+}
+
+// migrateV2 adds the token sources setting, with the default order
+func migrateV2(raw map[string]interface{}) error {
+	var sources []interface{}
+	for _, s := range tokensource.DefaultOrder {
+		sources = append(sources, s)
+	}
+	raw["token_sources"] = sources
+	return nil
+}
+
+// migrateV3 adds the preferences, with their default values
+func migrateV3(raw map[string]interface{}) error {
+	data, err := json.Marshal(DefaultPreferences())
+	if err != nil {
+		return err
+	}
+	var prefs map[string]interface{}
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return err
+	}
+	raw["preferences"] = prefs
+	return nil
+}
+
+// migrateV4 adds the restore session preference, which is off by default
+func migrateV4(raw map[string]interface{}) error {
+	v, ok := raw["preferences"]
+	if !ok {
+		return &ValidationError{Field: "preferences", Err: fmt.Errorf("missing")}
+	}
+	prefs, ok := v.(map[string]interface{})
+	if !ok {
+		return &ValidationError{Field: "preferences", Err: fmt.Errorf("must be an object, got %v", v)}
+	}
+	prefs["restore_session"] = false
+	return nil
+}
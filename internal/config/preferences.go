@@ -0,0 +1,135 @@
+// Editor and app preferences
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Editor wrap modes
+const (
+	WrapOff  = "off"  // long lines are clipped
+	WrapWord = "word" // long lines wrap at word boundaries
+	WrapChar = "char" // long lines wrap at any character
+)
+
+// App themes
+const (
+	ThemeSystem = "system" // follow the system light or dark setting
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Limits on preference values
+const (
+	MinAutosaveInterval = 5 // seconds
+	MinWindowWidth      = 300
+	MinWindowHeight     = 200
+)
+
+// Preferences are the user's editor and app preferences
+type Preferences struct {
+	WrapMode         string      `json:"wrap_mode"`
+	Theme            string      `json:"theme"`
+	NewGistFilename  string      `json:"new_gist_filename"`
+	NewGistContent   string      `json:"new_gist_content"`
+	NewGistPublic    bool        `json:"new_gist_public"`
+	Autosave         bool        `json:"autosave"`                  // save open documents with changes while they are edited
+	AutosaveInterval int         `json:"autosave_interval_seconds"` // seconds a document may have unsaved changes before it is autosaved
+	WindowSizes      WindowSizes `json:"window_sizes"`
+	RestoreSession   bool        `json:"restore_session"` // reopen the documents open when the app was last closed
+}
+
+// WindowSizes are the initial sizes of the app windows
+type WindowSizes struct {
+	Main    Size `json:"main"`
+	List    Size `json:"list"`
+	Editor  Size `json:"editor"`
+	History Size `json:"history"`
+}
+
+// Size is a window size, in Fyne's device independent pixels
+type Size struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// ParseSize parses a window size in the form WIDTHxHEIGHT, eg: 800x600
+func ParseSize(s string) (Size, error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	width, werr := strconv.Atoi(strings.TrimSpace(w))
+	height, herr := strconv.Atoi(strings.TrimSpace(h))
+	if !ok || werr != nil || herr != nil {
+		return Size{}, fmt.Errorf("size must be WIDTHxHEIGHT, eg: 800x600")
+	}
+	res := Size{Width: width, Height: height}
+	return res, res.validate()
+}
+
+// validate checks the size is no smaller than the minimum window size
+func (s Size) validate() error {
+	if s.Width < MinWindowWidth || s.Height < MinWindowHeight {
+		return fmt.Errorf("must be at least %dx%d, got %s", MinWindowWidth, MinWindowHeight, s)
+	}
+	return nil
+}
+
+// DefaultPreferences returns the default preferences
+func DefaultPreferences() Preferences {
+	return Preferences{
+		WrapMode:         WrapOff,
+		Theme:            ThemeSystem,
+		NewGistFilename:  "New Gist.md",
+		AutosaveInterval: 60,
+		WindowSizes: WindowSizes{
+			Main:    Size{600, 400},
+			List:    Size{800, 600},
+			Editor:  Size{800, 600},
+			History: Size{900, 600},
+		},
+	}
+}
+
+// Validate checks the preferences, and returns a *ValidationError for the first invalid setting
+func (p Preferences) Validate() error {
+	fieldErr := func(field string, err error) error {
+		return &ValidationError{Field: "preferences." + field, Err: err}
+	}
+	switch p.WrapMode {
+	case WrapOff, WrapWord, WrapChar:
+	default:
+		return fieldErr("wrap_mode", fmt.Errorf("must be %s, %s or %s, got %q", WrapOff, WrapWord, WrapChar, p.WrapMode))
+	}
+	switch p.Theme {
+	case ThemeSystem, ThemeLight, ThemeDark:
+	default:
+		return fieldErr("theme", fmt.Errorf("must be %s, %s or %s, got %q", ThemeSystem, ThemeLight, ThemeDark, p.Theme))
+	}
+	name := strings.TrimSpace(p.NewGistFilename)
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return fieldErr("new_gist_filename", fmt.Errorf("must be a filename, got %q", p.NewGistFilename))
+	}
+	if p.AutosaveInterval < MinAutosaveInterval {
+		return fieldErr("autosave_interval_seconds", fmt.Errorf("must be at least %d seconds", MinAutosaveInterval))
+	}
+	sizes := []struct {
+		name string
+		size Size
+	}{
+		{"main", p.WindowSizes.Main},
+		{"list", p.WindowSizes.List},
+		{"editor", p.WindowSizes.Editor},
+		{"history", p.WindowSizes.History},
+	}
+	for _, s := range sizes {
+		if err := s.size.validate(); err != nil {
+			return fieldErr("window_sizes."+s.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// Line-based text diffs
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of change for a diff line
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Line is a single line of a diff
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// String returns the line in unified diff format, prefixed with " ", "+" or "-"
+func (l Line) String() string {
+	switch l.Op {
+	case Insert:
+		return "+" + l.Text
+	case Delete:
+		return "-" + l.Text
+	default:
+		return " " + l.Text
+	}
+}
+
+// Lines returns the line-by-line diff from text a to text b.
+// It uses Myers' linear space algorithm, so that large texts can be compared.
+func Lines(a string, b string) []Line {
+	var res []Line
+	compare(splitLines(a), splitLines(b), &res)
+	return res
+}
+
+// compare appends the shortest diff from lines x to lines y to res
+func compare(x []string, y []string, res *[]Line) {
+	// Common lines at the start and end are unchanged
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	for _, l := range x[:pre] {
+		*res = append(*res, Line{Equal, l})
+	}
+	mx, my := x[pre:len(x)-suf], y[pre:len(y)-suf]
+	switch {
+	case len(mx) == 0:
+		for _, l := range my {
+			*res = append(*res, Line{Insert, l})
+		}
+	case len(my) == 0:
+		for _, l := range mx {
+			*res = append(*res, Line{Delete, l})
+		}
+	default:
+		// Both are changed at their first and last lines, so the edit distance is at least 2,
+		// and each side of the middle snake is a smaller problem
+		xs, ys, xe, ye := middleSnake(mx, my)
+		compare(mx[:xs], my[:ys], res)
+		for _, l := range mx[xs:xe] {
+			*res = append(*res, Line{Equal, l})
+		}
+		compare(mx[xe:], my[ye:], res)
+	}
+	for _, l := range x[len(x)-suf:] {
+		*res = append(*res, Line{Equal, l})
+	}
+}
+
+// middleSnake returns the middle snake of a shortest diff from x to y: a run of equal
+// lines x[xs:xe] == y[ys:ye] halfway along it, found by searching from both ends at once.
+func middleSnake(x []string, y []string) (xs int, ys int, xe int, ye int) {
+	n, m := len(x), len(y)
+	delta := n - m
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	// The furthest x reached on each diagonal k = x - y, from the start and from the end
+	fwd := make([]int, 2*limit+3)
+	bwd := make([]int, 2*limit+3)
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && fwd[offset+k-1] < fwd[offset+k+1]) {
+				i = fwd[offset+k+1]
+			} else {
+				i = fwd[offset+k-1] + 1
+			}
+			j := i - k
+			i0, j0 := i, j
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			fwd[offset+k] = i
+			if kb := delta - k; delta%2 != 0 && kb >= -(d-1) && kb <= d-1 && i+bwd[offset+kb] >= n {
+				return i0, j0, i, j
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && bwd[offset+k-1] < bwd[offset+k+1]) {
+				i = bwd[offset+k+1]
+			} else {
+				i = bwd[offset+k-1] + 1
+			}
+			j := i - k
+			i0, j0 := i, j
+			for i < n && j < m && x[n-1-i] == y[m-1-j] {
+				i++
+				j++
+			}
+			bwd[offset+k] = i
+			if kf := delta - k; delta%2 == 0 && kf >= -d && kf <= d && i+fwd[offset+kf] >= n {
+				return n - i, m - j, n - i0, m - j0
+			}
+		}
+	}
+	return 0, 0, 0, 0 // not reached: the paths always meet within limit
+}
+
+// HasChanges returns true if the diff contains any inserted or deleted lines
+func HasChanges(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Unified returns the diff from text a to text b in unified diff format,
+// showing all lines, with the given names as the file headers.
+// Returns an empty string if the texts are equal.
+func Unified(a string, b string, nameA string, nameB string) string {
+	lines := Lines(a, b)
+	if !HasChanges(lines) {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+	for _, l := range lines {
+		sb.WriteString(l.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines. An empty text has no lines.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// Conflict markers around the two versions of a conflicting change in a merge
+const (
+	ConflictStart = "<<<<<<< "
+	ConflictSep   = "======="
+	ConflictEnd   = ">>>>>>> "
+)
+
+// hunk is a change to the base text, replacing lines base[start:end] with lines
+type hunk struct {
+	start int
+	end   int
+	lines []string
+}
+
+// hunks returns the changes to the base text in a diff from it
+func hunks(diff []Line) []hunk {
+	var res []hunk
+	var cur *hunk
+	i := 0
+	for _, l := range diff {
+		if l.Op == Equal {
+			cur = nil
+			i++
+			continue
+		}
+		if cur == nil {
+			res = append(res, hunk{start: i, end: i})
+			cur = &res[len(res)-1]
+		}
+		if l.Op == Delete {
+			cur.end++
+			i++
+		} else {
+			cur.lines = append(cur.lines, l.Text)
+		}
+	}
+	return res
+}
+
+// apply returns the lines base[start:end] with the changes of the hunks, which must lie within them
+func apply(base []string, start int, end int, changes []hunk) []string {
+	var res []string
+	i := start
+	for _, h := range changes {
+		res = append(res, base[i:h.start]...)
+		res = append(res, h.lines...)
+		i = h.end
+	}
+	return append(res, base[i:end]...)
+}
+
+// Merge combines the changes made to the base text in texts a and b, with a three-way merge.
+// Where both texts change the same lines differently, both versions are kept between
+// conflict markers labelled with nameA and nameB, and conflicts is true.
+func Merge(base string, a string, b string, nameA string, nameB string) (merged string, conflicts bool) {
+	x := splitLines(base)
+	ha, hb := hunks(Lines(base, a)), hunks(Lines(base, b))
+	var res []string
+	i := 0
+	for len(ha) > 0 || len(hb) > 0 {
+		// The region starts at the first change, and grows while changes of either text overlap it
+		var start int
+		switch {
+		case len(hb) == 0 || (len(ha) > 0 && ha[0].start <= hb[0].start):
+			start = ha[0].start
+		default:
+			start = hb[0].start
+		}
+		end := start
+		na, nb := 0, 0
+		for grown := true; grown; {
+			grown = false
+			if na < len(ha) && ha[na].start <= end {
+				end = max(end, ha[na].end)
+				na++
+				grown = true
+			}
+			if nb < len(hb) && hb[nb].start <= end {
+				end = max(end, hb[nb].end)
+				nb++
+				grown = true
+			}
+		}
+		res = append(res, x[i:start]...)
+		va, vb := apply(x, start, end, ha[:na]), apply(x, start, end, hb[:nb])
+		switch {
+		case nb == 0:
+			res = append(res, va...)
+		case na == 0:
+			res = append(res, vb...)
+		case strings.Join(va, "\n") == strings.Join(vb, "\n"):
+			res = append(res, va...)
+		default:
+			conflicts = true
+			res = append(res, ConflictStart+nameA)
+			res = append(res, va...)
+			res = append(res, ConflictSep)
+			res = append(res, vb...)
+			res = append(res, ConflictEnd+nameB)
+		}
+		ha, hb = ha[na:], hb[nb:]
+		i = end
+	}
+	res = append(res, x[i:]...)
+	return strings.Join(res, "\n"), conflicts
+}
+
+// max returns the larger of two ints
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
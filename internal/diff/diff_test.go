@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	a := "line 1\nline 2\nline 3"
+	b := "line 1\nline two\nline 3\nline 4"
+	expect := []Line{
+		{Equal, "line 1"},
+		{Delete, "line 2"},
+		{Insert, "line two"},
+		{Equal, "line 3"},
+		{Insert, "line 4"},
+	}
+	assert.Equal(t, expect, Lines(a, b))
+}
+
+func TestLines_Empty(t *testing.T) {
+	assert.Equal(t, []Line{{Insert, "foo"}}, Lines("", "foo"))
+	assert.Equal(t, []Line{{Delete, "foo"}}, Lines("foo", ""))
+	assert.Empty(t, Lines("", ""))
+}
+
+func TestUnified(t *testing.T) {
+	assert.Equal(t, "", Unified("same", "same", "a", "b"), "equal texts should have no diff")
+
+	expect := "--- old\n+++ new\n foo\n-bar\n+baz\n"
+	assert.Equal(t, expect, Unified("foo\nbar", "foo\nbaz", "old", "new"))
+}
+
+func TestMerge(t *testing.T) {
+	base := "title\n\none\ntwo\nthree\n"
+
+	// Changes to different lines are combined
+	merged, conflicts := Merge(base, "Title\n\none\ntwo\nthree\n", "title\n\none\ntwo\nthree\nfour\n", "mine", "theirs")
+	assert.False(t, conflicts)
+	assert.Equal(t, "Title\n\none\ntwo\nthree\nfour\n", merged)
+
+	// The same change in both texts is not a conflict
+	merged, conflicts = Merge(base, "title\n\none\n2\nthree\n", "title\n\none\n2\nthree\n", "mine", "theirs")
+	assert.False(t, conflicts)
+	assert.Equal(t, "title\n\none\n2\nthree\n", merged)
+
+	// Different changes to the same lines keep both versions
+	merged, conflicts = Merge(base, "title\n\none\nmy two\nthree\n", "title\n\none\ntheir two\nthree\n", "mine", "theirs")
+	assert.True(t, conflicts)
+	assert.Equal(t, "title\n\none\n<<<<<<< mine\nmy two\n=======\ntheir two\n>>>>>>> theirs\nthree\n", merged)
+}
+
+func TestLines_Large(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20000; i++ {
+		a = append(a, fmt.Sprintf("line %d", i))
+		if i%1000 == 0 {
+			b = append(b, fmt.Sprintf("changed %d", i))
+		} else {
+			b = append(b, fmt.Sprintf("line %d", i))
+		}
+	}
+	lines := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	assert.Len(t, lines, 20020)
+	changed := 0
+	for _, l := range lines {
+		if l.Op != Equal {
+			changed++
+		}
+	}
+	assert.Equal(t, 40, changed, "only the changed lines should differ")
+}
@@ -4,6 +4,7 @@ package editor
 import (
 	"reflect"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/widget"
@@ -18,12 +19,31 @@ func NewMultilineWidget(content string) *MultiLineWidget {
 	e.SetText(content)
 	e.MultiLine = true
 	e.Wrapping = fyne.TextWrap(fyne.TextTruncateClip)
+	e.ResetHistory()
 	return e
 }
 
+// Limits of the undo history
+var (
+	maxUndo        = 500         // versions kept
+	undoGroupDelay = time.Second // changes closer together than this are undone together
+)
+
 // MultiLineWidget is a custom multiline entry widget, with improved cursor functions
+// and an undo history
 type MultiLineWidget struct {
 	widget.Entry
+	history    []version // earlier versions of the text, and undone versions after position
+	position   int       // index of the current text in history
+	recordedAt time.Time // when the current version was recorded
+	restoring  bool      // true while undoing or redoing, which is not a new change
+}
+
+// version is a version of the text in the undo history, with its cursor position
+type version struct {
+	text   string
+	row    int
+	column int
 }
 
 // Content returns the editor's text content
@@ -85,16 +105,76 @@ func (m *MultiLineWidget) RowCount() int {
 	return strings.Count(m.Text, "\n")
 }
 
+// ResetHistory clears the undo history, starting it from the current text.
+// Call it after loading new content, which should not be undone.
+func (m *MultiLineWidget) ResetHistory() {
+	m.history = []version{m.version()}
+	m.position = 0
+	m.recordedAt = time.Time{}
+}
+
+// Record adds the current text to the undo history, and drops any undone versions.
+// Call it when the text is changed, such as from OnChanged.
+func (m *MultiLineWidget) Record() {
+	if m.restoring || m.history[m.position].text == m.Text {
+		return
+	}
+	m.history = m.history[:m.position+1]
+	now := time.Now()
+	if m.position > 0 && now.Sub(m.recordedAt) < undoGroupDelay {
+		m.history[m.position] = m.version() // typing continues the current change
+	} else {
+		m.history = append(m.history, m.version())
+		m.position++
+	}
+	m.recordedAt = now
+	if len(m.history) > maxUndo {
+		m.history = m.history[1:]
+		m.position--
+	}
+}
+
 // Undo the most recent changes to the text content
 func (m *MultiLineWidget) Undo() {
-	logger.Debug("placeholder for Undo action")
-	// TODO
+	if m.position == 0 {
+		return
+	}
+	m.position--
+	m.restore(m.history[m.position])
 }
 
 // Redo the most recent changes to the text content
 func (m *MultiLineWidget) Redo() {
-	logger.Debug("placeholder for Redo action")
-	// TODO
+	if m.position == len(m.history)-1 {
+		return
+	}
+	m.position++
+	m.restore(m.history[m.position])
+}
+
+// CanUndo returns true if there are changes to undo
+func (m *MultiLineWidget) CanUndo() bool {
+	return m.position > 0
+}
+
+// CanRedo returns true if there are undone changes to redo
+func (m *MultiLineWidget) CanRedo() bool {
+	return m.position < len(m.history)-1
+}
+
+// version returns the current text and cursor position
+func (m *MultiLineWidget) version() version {
+	return version{text: m.Text, row: m.CursorRow, column: m.CursorColumn}
+}
+
+// restore shows a version from the undo history
+func (m *MultiLineWidget) restore(v version) {
+	m.restoring = true
+	defer func() { m.restoring = false }()
+	m.SetText(v.text)
+	m.CursorRow, m.CursorColumn = v.row, v.column
+	m.recordedAt = time.Time{} // the next change starts a new version
+	m.Refresh()
 }
 
 // Debug current text selection
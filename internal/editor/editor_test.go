@@ -3,6 +3,7 @@ package editor
 
 import (
 	"testing"
+	"time"
 
 	"fyne.io/fyne/v2/app"
 	"github.com/stretchr/testify/assert"
@@ -41,3 +42,41 @@ func Test_SelectionStart(t *testing.T) {
 	assert.Equal(t, 1, pos.Row, "no selection - selection row position should be 1")
 	assert.Equal(t, 1, pos.Col, "no selection - selection col position should be 1")
 }
+
+func Test_UndoRedo(t *testing.T) {
+	undoGroupDelay = 0
+	defer func() { undoGroupDelay = time.Second }()
+	e := newTestMultiLine()
+	e.ResetHistory()
+	assert.False(t, e.CanUndo(), "loaded content should not be undone")
+
+	e.SetText("one")
+	e.Record()
+	e.SetText("two")
+	e.Record()
+	e.Undo()
+	assert.Equal(t, "one", e.Text)
+	e.Undo()
+	assert.Equal(t, "foo\nbar\nbaz\nbuz", e.Text)
+	e.Undo()
+	assert.Equal(t, "foo\nbar\nbaz\nbuz", e.Text, "undo stops at the start of the history")
+
+	e.Redo()
+	assert.Equal(t, "one", e.Text)
+	e.SetText("three")
+	e.Record()
+	assert.False(t, e.CanRedo(), "a new change drops the undone versions")
+	e.Undo()
+	assert.Equal(t, "one", e.Text)
+}
+
+func Test_UndoGroupsTyping(t *testing.T) {
+	e := newTestMultiLine()
+	e.ResetHistory()
+	e.SetText("a")
+	e.Record()
+	e.SetText("ab")
+	e.Record()
+	e.Undo()
+	assert.Equal(t, "foo\nbar\nbaz\nbuz", e.Text, "quick changes should be undone together")
+}
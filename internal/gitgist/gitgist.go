@@ -0,0 +1,254 @@
+// Package gitgist edits gists as local git clones.
+// Gists are git repositories: working in a clone allows binary files, such as
+// images, which the REST API can't store. Saves are committed, then pushed.
+package gitgist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// Backend clones gists into a local directory, one working directory per gist
+type Backend struct {
+	Dir       string                 // directory holding the clones
+	RemoteURL func(id string) string // returns the git remote URL of a gist
+	Token     string                 // Github token for fetching and pushing; may be empty for local remotes
+	Author    string                 // Github login, used as the commit author
+}
+
+// New returns a Backend cloning into dir, using the gist remotes of the configured Github host
+func (b Backend) New(cfg github.GithubConfig, dir string) *Backend {
+	return &Backend{
+		Dir:       dir,
+		RemoteURL: cfg.GistRemoteURL,
+		Token:     cfg.GithubAPIToken,
+	}
+}
+
+// Repo is a local clone of a gist
+type Repo struct {
+	ID      string
+	Dir     string
+	backend *Backend
+}
+
+// Clone clones a gist into its working directory, or pulls the latest changes
+// if it has already been cloned
+func (b *Backend) Clone(id string) (*Repo, error) {
+	if id == "" {
+		return nil, fmt.Errorf("clone gist: gist has no ID")
+	}
+	r := &Repo{ID: id, Dir: filepath.Join(b.Dir, id), backend: b}
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); err == nil {
+		logger.Debug("gist %s already cloned: pulling", id)
+		return r, r.Pull()
+	}
+	if err := os.MkdirAll(b.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("create clone dir failed: %w", err)
+	}
+	if _, err := b.git(b.Dir, "clone", b.RemoteURL(id), id); err != nil {
+		return nil, fmt.Errorf("clone gist %s: %w", id, err)
+	}
+	logger.Info("cloned gist %s to %s", id, r.Dir)
+	return r, nil
+}
+
+// Gist returns the text files of the working directory as a gist.
+// Binary files are not included; see BinaryFiles.
+func (r *Repo) Gist() (github.Gist, error) {
+	names, err := r.files()
+	if err != nil {
+		return github.Gist{}, err
+	}
+	g := github.Gist{ID: r.ID}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(r.Dir, name))
+		if err != nil {
+			return github.Gist{}, fmt.Errorf("read %s: %w", name, err)
+		}
+		if isBinary(data) {
+			continue
+		}
+		if err := g.AddFile(name, string(data)); err != nil {
+			return github.Gist{}, err
+		}
+	}
+	return g, nil
+}
+
+// BinaryFiles returns the names of the binary files in the working directory
+func (r *Repo) BinaryFiles() ([]string, error) {
+	names, err := r.files()
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(r.Dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		if isBinary(data) {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+// Save writes the gist's files to the working directory, and commits the changes.
+// Text files not in the gist are removed; binary files are kept.
+// Returns false if there was nothing to commit.
+func (r *Repo) Save(g github.Gist, message string) (bool, error) {
+	existing, err := r.files()
+	if err != nil {
+		return false, err
+	}
+	for _, name := range existing {
+		if g.FileIndex(name) >= 0 {
+			continue
+		}
+		fp := filepath.Join(r.Dir, name)
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return false, fmt.Errorf("read %s: %w", name, err)
+		}
+		if isBinary(data) {
+			continue
+		}
+		if err := os.Remove(fp); err != nil {
+			return false, fmt.Errorf("remove %s: %w", name, err)
+		}
+	}
+	for _, f := range g.Files {
+		if err := checkFilename(f.Filename); err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(filepath.Join(r.Dir, f.Filename), []byte(f.Content), 0644); err != nil {
+			return false, fmt.Errorf("write %s: %w", f.Filename, err)
+		}
+	}
+	return r.commit(message)
+}
+
+// AddFile copies a local file, which may be binary, into the working directory
+// and commits it. Returns the name of the added file.
+func (r *Repo) AddFile(src string) (string, error) {
+	name := filepath.Base(src)
+	if err := checkFilename(name); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", src, err)
+	}
+	if err := os.WriteFile(filepath.Join(r.Dir, name), data, 0644); err != nil {
+		return "", fmt.Errorf("write %s: %w", name, err)
+	}
+	if _, err := r.commit("Add " + name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Push pushes the committed changes to the gist
+func (r *Repo) Push() error {
+	if _, err := r.backend.git(r.Dir, "push", "origin", "HEAD"); err != nil {
+		return fmt.Errorf("push gist %s: %w", r.ID, err)
+	}
+	logger.Info("pushed gist %s", r.ID)
+	return nil
+}
+
+// Pull fetches and merges the latest changes to the gist.
+// Only fast-forward merges are made; diverged changes return an error.
+func (r *Repo) Pull() error {
+	if _, err := r.backend.git(r.Dir, "pull", "--ff-only", "origin"); err != nil {
+		return fmt.Errorf("pull gist %s: %w", r.ID, err)
+	}
+	return nil
+}
+
+// commit stages all changes and commits them. Returns false if there was nothing to commit.
+func (r *Repo) commit(message string) (bool, error) {
+	if _, err := r.backend.git(r.Dir, "add", "-A"); err != nil {
+		return false, fmt.Errorf("stage changes: %w", err)
+	}
+	status, err := r.backend.git(r.Dir, "status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("get status: %w", err)
+	}
+	if strings.TrimSpace(status) == "" {
+		return false, nil
+	}
+	if _, err := r.backend.git(r.Dir, "commit", "-m", message); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	logger.Debug("committed gist %s: %s", r.ID, message)
+	return true, nil
+}
+
+// files returns the names of the files in the working directory, in alphabetical order.
+// Gists can't contain directories, so only top-level files are returned.
+func (r *Repo) files() ([]string, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("read clone dir: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == ".git" {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// git runs a git command in the given directory, and returns its output.
+// The token is passed as a request header through the environment, so that it isn't
+// stored in the clone's config or shown in the command line of the process.
+func (b *Backend) git(dir string, args ...string) (string, error) {
+	var pre []string
+	if b.Author != "" {
+		pre = append(pre, "-c", "user.name="+b.Author, "-c", "user.email="+b.Author+"@users.noreply.github.com")
+	}
+	cmd := exec.Command("git", append(pre, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if b.Token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + b.Token))
+		cmd.Env = append(cmd.Env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// checkFilename returns an error if the name is not a valid gist filename
+func checkFilename(name string) error {
+	if name == "" || name == "." || name == ".." || name == ".git" || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid filename: %q", name)
+	}
+	return nil
+}
+
+// isBinary returns true if the data is not valid UTF-8 text
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
@@ -0,0 +1,107 @@
+// Multiple named Github accounts
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Name of the account created when none exist
+const DefaultAccountName = "default"
+
+// Accounts is a list of named Github accounts, each with its own host and token,
+// one of which is active
+type Accounts struct {
+	List   []GithubConfig
+	Active string // name of the active account
+}
+
+// Names returns the account names, in order
+func (a Accounts) Names() []string {
+	var res []string
+	for _, x := range a.List {
+		res = append(res, x.Name)
+	}
+	return res
+}
+
+// Get returns the named account, or nil if not found
+func (a *Accounts) Get(name string) *GithubConfig {
+	for i := range a.List {
+		if a.List[i].Name == name {
+			return &a.List[i]
+		}
+	}
+	return nil
+}
+
+// ActiveAccount returns the active account, or the first account if none is active.
+// Returns nil if there are no accounts.
+func (a *Accounts) ActiveAccount() *GithubConfig {
+	if x := a.Get(a.Active); x != nil {
+		return x
+	}
+	if len(a.List) == 0 {
+		return nil
+	}
+	return &a.List[0]
+}
+
+// ValidAccountName returns an error if an account name is empty, or can't be used as a
+// directory name, as the account's gist clones are kept in a directory of that name
+func ValidAccountName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("account name is empty")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || strings.Contains(name, "..") {
+		return fmt.Errorf("account name %q can't contain path separators or \"..\"", name)
+	}
+	return nil
+}
+
+// Add adds a new account. Account names must be unique and valid.
+func (a *Accounts) Add(c GithubConfig) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if err := ValidAccountName(c.Name); err != nil {
+		return err
+	}
+	if a.Get(c.Name) != nil {
+		return fmt.Errorf("an account named %s already exists", c.Name)
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	a.List = append(a.List, c)
+	return nil
+}
+
+// Remove removes the named account. The last account can't be removed.
+// If the active account is removed, the first remaining account becomes active.
+func (a *Accounts) Remove(name string) error {
+	if a.Get(name) == nil {
+		return fmt.Errorf("account not found: %s", name)
+	}
+	if len(a.List) == 1 {
+		return fmt.Errorf("the last account can't be removed")
+	}
+	var list []GithubConfig
+	for _, x := range a.List {
+		if x.Name != name {
+			list = append(list, x)
+		}
+	}
+	a.List = list
+	if a.Active == name {
+		a.Active = a.List[0].Name
+	}
+	return nil
+}
+
+// SetActive makes the named account active
+func (a *Accounts) SetActive(name string) error {
+	if a.Get(name) == nil {
+		return fmt.Errorf("account not found: %s", name)
+	}
+	a.Active = name
+	return nil
+}
@@ -0,0 +1,33 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccounts(t *testing.T) {
+	a := Accounts{}
+	assert.Nil(t, a.ActiveAccount())
+
+	require.Nil(t, a.Add(GithubConfig{Name: "personal"}))
+	work := EnterpriseConfig("github.example.com")
+	work.Name = "work"
+	require.Nil(t, a.Add(work))
+	assert.NotNil(t, a.Add(GithubConfig{Name: "work"}), "names should be unique")
+	assert.NotNil(t, a.Add(GithubConfig{Name: " "}), "names should not be empty")
+	assert.NotNil(t, a.Add(GithubConfig{Name: "../work"}), "names should be usable as directory names")
+	assert.NotNil(t, a.Add(GithubConfig{Name: `a\b`}))
+	assert.NotNil(t, a.Add(GithubConfig{Name: "bad", APIBaseURL: "github.example.com"}))
+	assert.Equal(t, []string{"personal", "work"}, a.Names())
+
+	assert.Equal(t, "personal", a.ActiveAccount().Name, "the first account should be active by default")
+	require.Nil(t, a.SetActive("work"))
+	assert.Equal(t, "https://github.example.com/api/v3", a.ActiveAccount().APIURL())
+	assert.NotNil(t, a.SetActive("missing"))
+
+	require.Nil(t, a.Remove("work"))
+	assert.Equal(t, "personal", a.Active, "removing the active account should activate another")
+	assert.NotNil(t, a.Remove("personal"), "the last account can't be removed")
+}
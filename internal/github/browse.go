@@ -0,0 +1,31 @@
+// Browsing other users' gists and the public timeline
+package github
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Github usernames are alphanumeric, with single hyphens
+var usernameRgx = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9]|-[A-Za-z0-9]){0,38}$`)
+
+// ValidUsername returns an error if the name is not a valid Github username
+func ValidUsername(name string) error {
+	if !usernameRgx.MatchString(name) {
+		return fmt.Errorf("invalid Github username: %q", name)
+	}
+	return nil
+}
+
+// ListUserGists returns a page of a user's public gists
+func (c *Client) ListUserGists(username string, opts ListOptions) (GistPage, error) {
+	if err := ValidUsername(username); err != nil {
+		return GistPage{}, err
+	}
+	return c.listGistsPage("/users/"+username+"/gists", opts)
+}
+
+// ListPublicGists returns a page of the public gist timeline, most recently updated first
+func (c *Client) ListPublicGists(opts ListOptions) (GistPage, error) {
+	return c.listGistsPage("/gists/public", opts)
+}
@@ -0,0 +1,43 @@
+package github
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListUserGists(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/users/octo-cat/gists", r.URL.Path)
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		w.Write([]byte("[" + exampleGistJSON + "]"))
+	})
+	res, err := c.ListUserGists("octo-cat", ListOptions{Page: 2})
+	require.Nil(t, err)
+	require.Len(t, res.Gists, 1)
+	assert.Equal(t, "octocat", res.Gists[0].AuthorId)
+
+	_, err = c.ListUserGists("../user", ListOptions{})
+	assert.NotNil(t, err, "invalid usernames should be rejected")
+}
+
+func TestClient_ListPublicGists(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists/public", r.URL.Path)
+		w.Write([]byte("[" + exampleGistJSON + "]"))
+	})
+	res, err := c.ListPublicGists(ListOptions{})
+	require.Nil(t, err)
+	assert.Len(t, res.Gists, 1)
+}
+
+func TestValidUsername(t *testing.T) {
+	assert.Nil(t, ValidUsername("octocat"))
+	assert.Nil(t, ValidUsername("octo-cat1"))
+	assert.NotNil(t, ValidUsername(""))
+	assert.NotNil(t, ValidUsername("-octocat"))
+	assert.NotNil(t, ValidUsername("octo--cat"))
+	assert.NotNil(t, ValidUsername("octo/cat"))
+}
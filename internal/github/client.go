@@ -0,0 +1,247 @@
+// Github Gists REST API client
+package github
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/avelino/slugify"
+)
+
+// Default base URL of the Github REST API
+const DefaultAPIBaseURL = "https://api.github.com"
+
+// Default base URL of the Github web host
+const DefaultWebBaseURL = "https://github.com"
+
+// Client is a client for the Github Gists REST API.
+// The BaseURL may be changed to point at a test server.
+//
+// GET responses are cached, and repeated requests are sent as conditional
+// requests, which don't count against the rate limit when nothing has changed.
+type Client struct {
+	BaseURL     string
+	Token       string
+	HTTPClient  *http.Client
+	MaxRetries  int             // retries after hitting a secondary rate limit
+	OnRateLimit func(RateLimit) // called with the rate limit status after each response
+
+	mu         sync.Mutex
+	cache      map[string]*list.Element // cached GET responses, by URL
+	cacheOrder *list.List               // the cached responses, most recently used first
+	rateLimit  RateLimit
+	sleep      func(time.Duration) // waits before a retry; replaced in tests
+}
+
+// NewClient returns a new Client for the public Github API, authenticated with the given token
+func NewClient(token string) *Client {
+	return &Client{
+		BaseURL:    DefaultAPIBaseURL,
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		MaxRetries: 3,
+		cache:      map[string]*list.Element{},
+		cacheOrder: list.New(),
+		sleep:      time.Sleep,
+	}
+}
+
+// APIError is an error response returned by the Github API
+type APIError struct {
+	StatusCode       int
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("github api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("github api: %d %s", e.StatusCode, e.Message)
+}
+
+// ServerInfo describes the Github host the client is connected to
+type ServerInfo struct {
+	InstalledVersion string // the Github Enterprise Server version; empty for github.com
+}
+
+// Ping checks the connection to the API host, and returns information about the server.
+// The request does not require authentication.
+func (c *Client) Ping() (ServerInfo, error) {
+	var res struct {
+		InstalledVersion string `json:"installed_version"`
+	}
+	if err := c.do(http.MethodGet, "/meta", nil, &res); err != nil {
+		return ServerInfo{}, err
+	}
+	return ServerInfo{InstalledVersion: res.InstalledVersion}, nil
+}
+
+// ListGists returns a page of the authenticated user's gists, most recently updated first
+func (c *Client) ListGists(opts ListOptions) (GistPage, error) {
+	return c.listGistsPage("/gists", opts)
+}
+
+// ListUpdatedGists returns all of the authenticated user's gists updated since the given time
+func (c *Client) ListUpdatedGists(since time.Time) ([]Gist, error) {
+	return c.listAllPages("/gists", ListOptions{Since: since})
+}
+
+// GetGist returns a single gist by ID
+func (c *Client) GetGist(id string) (Gist, error) {
+	var res apiGist
+	if err := c.do(http.MethodGet, "/gists/"+id, nil, &res); err != nil {
+		return Gist{}, err
+	}
+	return res.toGist(), nil
+}
+
+// CreateGist creates a new gist, and returns the gist as stored on Github.
+// The gist's visibility can only be set when it is created.
+func (c *Client) CreateGist(g Gist) (Gist, error) {
+	public := g.Public
+	body := apiGistRequest{
+		Description: &g.Description,
+		Public:      &public,
+		Files:       map[string]*apiFile{},
+	}
+	for _, f := range g.Files {
+		body.Files[f.Filename] = &apiFile{Content: f.Content}
+	}
+	var res apiGist
+	if err := c.do(http.MethodPost, "/gists", body, &res); err != nil {
+		return Gist{}, err
+	}
+	return res.toGist(), nil
+}
+
+// UpdateGist saves all changes to an existing gist's files in a single update,
+// including added, renamed and removed files.
+// Returns the gist as stored on Github.
+func (c *Client) UpdateGist(g Gist) (Gist, error) {
+	if g.IsNew() {
+		return Gist{}, fmt.Errorf("update gist: gist has no ID")
+	}
+	if err := g.CheckComplete(); err != nil {
+		return Gist{}, err
+	}
+	body := apiGistRequest{Description: &g.Description, Files: updatedFiles(g)}
+	var res apiGist
+	if err := c.do(http.MethodPatch, "/gists/"+g.ID, body, &res); err != nil {
+		return Gist{}, err
+	}
+	return res.toGist(), nil
+}
+
+// updatedFiles returns the files payload for a gist update.
+// Files are keyed by their filename on Github. Renamed files carry their new
+// filename, and removed files are set to null.
+func updatedFiles(g Gist) map[string]*apiFile {
+	files := map[string]*apiFile{}
+	for _, name := range g.removed {
+		files[name] = nil
+	}
+	// A new file may reuse the name of a removed file, replacing its content
+	for _, f := range g.Files {
+		if f.originalName == "" {
+			files[f.Filename] = &apiFile{Content: f.Content}
+			continue
+		}
+		x := &apiFile{Content: f.Content}
+		if f.originalName != f.Filename {
+			x.Filename = f.Filename
+		}
+		files[f.originalName] = x
+	}
+	return files
+}
+
+// DeleteGist deletes a gist by ID
+func (c *Client) DeleteGist(id string) error {
+	return c.do(http.MethodDelete, "/gists/"+id, nil, nil)
+}
+
+// apiGist is the JSON representation of a gist in API responses
+type apiGist struct {
+	ID          string              `json:"id"`
+	HTMLURL     string              `json:"html_url"`
+	Description *string             `json:"description"`
+	Public      bool                `json:"public"`
+	Files       map[string]*apiFile `json:"files"`
+	Owner       *apiUser            `json:"owner"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Truncated   bool                `json:"truncated"`
+}
+
+// apiFile is the JSON representation of a single gist file.
+// Only the filename and content are sent in requests.
+type apiFile struct {
+	Filename  string `json:"filename,omitempty"`
+	Content   string `json:"content,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	RawURL    string `json:"raw_url,omitempty"`
+}
+
+// apiUser is the JSON representation of a Github user
+type apiUser struct {
+	Login string `json:"login"`
+	Name  string `json:"name,omitempty"`
+}
+
+// apiGistRequest is the JSON body for creating or updating a gist
+type apiGistRequest struct {
+	Description *string             `json:"description,omitempty"`
+	Public      *bool               `json:"public,omitempty"` // only used on create
+	Files       map[string]*apiFile `json:"files"`
+}
+
+// toGist converts an API gist to a Gist, with files in alphabetical order
+func (a apiGist) toGist() Gist {
+	g := Gist{
+		ID:        a.ID,
+		URL:       a.HTMLURL,
+		Public:    a.Public,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Truncated: a.Truncated,
+	}
+	if a.Description != nil { // null for gists without a description
+		g.Description = *a.Description
+	}
+	if a.Owner != nil {
+		g.AuthorId = a.Owner.Login
+	}
+	var names []string
+	for name := range a.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := a.Files[name]
+		g.Files = append(g.Files, File{
+			Filename:     name,
+			Content:      f.Content,
+			Size:         f.Size,
+			RawURL:       f.RawURL,
+			Truncated:    f.Truncated || (f.Content == "" && f.Size > 0), // very large files have no inline content
+			originalName: name,
+		})
+	}
+	g.Slug = slugify.Slugify(g.Title())
+	return g
+}
+
+// toGists converts a list of API gists to Gists
+func toGists(a []apiGist) []Gist {
+	res := make([]Gist, 0, len(a))
+	for _, x := range a {
+		res = append(res, x.toGist())
+	}
+	return res
+}
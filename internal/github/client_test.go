@@ -0,0 +1,167 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exampleGistJSON = `{
+	"id": "abc123",
+	"html_url": "https://gist.github.com/octocat/abc123",
+	"description": "A greeting",
+	"public": true,
+	"owner": {"login": "octocat"},
+	"created_at": "2023-01-02T03:04:05Z",
+	"updated_at": "2023-02-03T04:05:06Z",
+	"files": {
+		"hello.md": {"filename": "hello.md", "content": "# Hello"}
+	}
+}`
+
+var multiFileGistJSON = `{
+	"id": "abc123",
+	"files": {
+		"script.sh": {"filename": "script.sh", "content": "echo hi"},
+		"README.md": {"filename": "README.md", "content": "# Readme"},
+		"config.yml": {"filename": "config.yml", "content": "a: 1"}
+	}
+}`
+
+// Return a test client for the given handler; the server is closed when the test ends
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := NewClient("test-token")
+	c.BaseURL = srv.URL
+	return c
+}
+
+func TestClient_GetGist(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/gists/abc123", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		w.Write([]byte(exampleGistJSON))
+	})
+	g, err := c.GetGist("abc123")
+	require.Nil(t, err)
+	assert.Equal(t, "abc123", g.ID)
+	require.Len(t, g.Files, 1)
+	assert.Equal(t, "hello.md", g.Files[0].Filename)
+	assert.Equal(t, "# Hello", g.Files[0].Content)
+	assert.Equal(t, "octocat", g.AuthorId)
+	assert.Equal(t, 2023, g.CreatedAt.Year())
+}
+
+func TestClient_ListGists(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists", r.URL.Path)
+		w.Write([]byte("[" + exampleGistJSON + "]"))
+	})
+	res, err := c.ListGists(ListOptions{})
+	require.Nil(t, err)
+	require.Len(t, res.Gists, 1)
+	assert.Equal(t, "hello.md", res.Gists[0].Title())
+	assert.Equal(t, 0, res.NextPage)
+}
+
+func TestClient_CreateGist(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/gists", r.URL.Path)
+		var body map[string]interface{}
+		data, _ := io.ReadAll(r.Body)
+		require.Nil(t, json.Unmarshal(data, &body))
+		assert.Equal(t, true, body["public"])
+		assert.Equal(t, "greetings", body["description"])
+		files := body["files"].(map[string]interface{})
+		assert.Equal(t, "# Hello", files["hello.md"].(map[string]interface{})["content"])
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(exampleGistJSON))
+	})
+	g := Gist{}.New("hello.md", "# Hello")
+	assert.False(t, g.Public, "new gists should be secret by default")
+	g.Public = true
+	g.Description = "greetings"
+	g, err := c.CreateGist(g)
+	require.Nil(t, err)
+	assert.Equal(t, "abc123", g.ID)
+	assert.True(t, g.Public)
+	assert.Equal(t, "A greeting", g.Description)
+}
+
+func TestClient_UpdateGist(t *testing.T) {
+	var body apiGistRequest
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(multiFileGistJSON))
+			return
+		}
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/gists/abc123", r.URL.Path)
+		data, _ := io.ReadAll(r.Body)
+		require.Nil(t, json.Unmarshal(data, &body))
+		w.Write([]byte(exampleGistJSON))
+	})
+	_, err := c.UpdateGist(Gist{}.New("hello.md", "# Hello"))
+	assert.NotNil(t, err, "updating a gist without an ID should fail")
+
+	// Edit, add, rename and remove files, then save in a single update
+	g, err := c.GetGist("abc123")
+	require.Nil(t, err)
+	g.File("README.md").Content = "updated"
+	require.Nil(t, g.AddFile("new.sh", "echo new"))
+	require.Nil(t, g.RenameFile("config.yml", "settings.yml"))
+	require.Nil(t, g.RemoveFile("script.sh"))
+	g.Description = "updated description"
+	_, err = c.UpdateGist(g)
+	require.Nil(t, err)
+
+	require.NotNil(t, body.Description)
+	assert.Equal(t, "updated description", *body.Description)
+	assert.Nil(t, body.Public, "visibility can't be changed after creation")
+	files := body.Files
+	assert.Equal(t, &apiFile{Content: "updated"}, files["README.md"])
+	assert.Equal(t, &apiFile{Content: "echo new"}, files["new.sh"])
+	assert.Equal(t, &apiFile{Filename: "settings.yml", Content: "a: 1"}, files["config.yml"])
+	v, ok := files["script.sh"]
+	assert.True(t, ok, "removed file should be sent")
+	assert.Nil(t, v, "removed file should be null")
+}
+
+func TestClient_DeleteGist(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	assert.Nil(t, c.DeleteGist("abc123"))
+}
+
+func TestClient_APIError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Not Found"}`))
+	})
+	_, err := c.GetGist("missing")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr), "error should be an APIError")
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "Not Found", apiErr.Message)
+}
+
+func TestClient_Ping(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/meta", r.URL.Path)
+		w.Write([]byte(`{"installed_version": "3.10.0"}`))
+	})
+	info, err := c.Ping()
+	require.Nil(t, err)
+	assert.Equal(t, "3.10.0", info.InstalledVersion)
+}
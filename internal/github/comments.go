@@ -0,0 +1,109 @@
+// Gist comments
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Comment is a comment on a gist
+type Comment struct {
+	ID        int64
+	Body      string // markdown content
+	Author    string // login name of the comment author
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// User is a Github user account
+type User struct {
+	Login string
+	Name  string
+}
+
+// GetAuthenticatedUser returns the user the client's token belongs to
+func (c *Client) GetAuthenticatedUser() (User, error) {
+	var res apiUser
+	if err := c.do(http.MethodGet, "/user", nil, &res); err != nil {
+		return User{}, err
+	}
+	return User{Login: res.Login, Name: res.Name}, nil
+}
+
+// ListComments returns the comments on a gist, oldest first. Every page is fetched.
+func (c *Client) ListComments(gistID string) ([]Comment, error) {
+	var res []apiComment
+	err := c.eachPage("/gists/"+gistID+"/comments", ListOptions{}, func(resp *response) error {
+		var page []apiComment
+		if err := decodeResponse(resp, &page); err != nil {
+			return err
+		}
+		res = append(res, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	comments := make([]Comment, 0, len(res))
+	for _, x := range res {
+		comments = append(comments, x.toComment())
+	}
+	return comments, nil
+}
+
+// CreateComment posts a new comment on a gist
+func (c *Client) CreateComment(gistID string, body string) (Comment, error) {
+	var res apiComment
+	if err := c.do(http.MethodPost, "/gists/"+gistID+"/comments", apiCommentRequest{Body: body}, &res); err != nil {
+		return Comment{}, err
+	}
+	return res.toComment(), nil
+}
+
+// EditComment replaces the body of an existing comment
+func (c *Client) EditComment(gistID string, commentID int64, body string) (Comment, error) {
+	var res apiComment
+	if err := c.do(http.MethodPatch, commentPath(gistID, commentID), apiCommentRequest{Body: body}, &res); err != nil {
+		return Comment{}, err
+	}
+	return res.toComment(), nil
+}
+
+// DeleteComment deletes a comment from a gist
+func (c *Client) DeleteComment(gistID string, commentID int64) error {
+	return c.do(http.MethodDelete, commentPath(gistID, commentID), nil, nil)
+}
+
+// commentPath returns the API path of a single comment
+func commentPath(gistID string, commentID int64) string {
+	return fmt.Sprintf("/gists/%s/comments/%d", gistID, commentID)
+}
+
+// apiComment is the JSON representation of a gist comment
+type apiComment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	User      *apiUser  `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// apiCommentRequest is the JSON body for creating or editing a comment
+type apiCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// toComment converts an API comment to a Comment
+func (a apiComment) toComment() Comment {
+	c := Comment{
+		ID:        a.ID,
+		Body:      a.Body,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+	if a.User != nil {
+		c.Author = a.User.Login
+	}
+	return c
+}
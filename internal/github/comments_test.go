@@ -0,0 +1,90 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exampleCommentJSON = `{
+	"id": 42,
+	"body": "Looks **good**",
+	"user": {"login": "octocat"},
+	"created_at": "2023-01-02T03:04:05Z",
+	"updated_at": "2023-01-02T03:04:05Z"
+}`
+
+func TestClient_ListComments(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists/abc123/comments", r.URL.Path)
+		w.Write([]byte("[" + exampleCommentJSON + "]"))
+	})
+	res, err := c.ListComments("abc123")
+	require.Nil(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, int64(42), res[0].ID)
+	assert.Equal(t, "Looks **good**", res[0].Body)
+	assert.Equal(t, "octocat", res[0].Author)
+}
+
+func TestClient_ListCommentsPages(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/gists/abc123/comments?page=2>; rel="next"`, srvURL))
+		}
+		w.Write([]byte(fmt.Sprintf(`[{"id": %s}]`, page)))
+	})
+	srvURL = c.BaseURL
+	res, err := c.ListComments("abc123")
+	require.Nil(t, err)
+	require.Len(t, res, 2, "all pages should be fetched")
+	assert.Equal(t, int64(2), res[1].ID)
+}
+
+func TestClient_CreateEditDeleteComment(t *testing.T) {
+	var requests []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var body apiCommentRequest
+		data, _ := io.ReadAll(r.Body)
+		require.Nil(t, json.Unmarshal(data, &body))
+		assert.Equal(t, "Looks **good**", body.Body)
+		w.Write([]byte(exampleCommentJSON))
+	})
+	created, err := c.CreateComment("abc123", "Looks **good**")
+	require.Nil(t, err)
+	assert.Equal(t, int64(42), created.ID)
+
+	_, err = c.EditComment("abc123", 42, "Looks **good**")
+	require.Nil(t, err)
+	require.Nil(t, c.DeleteComment("abc123", 42))
+
+	expect := []string{
+		"POST /gists/abc123/comments",
+		"PATCH /gists/abc123/comments/42",
+		"DELETE /gists/abc123/comments/42",
+	}
+	assert.Equal(t, expect, requests)
+}
+
+func TestClient_GetAuthenticatedUser(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/user", r.URL.Path)
+		w.Write([]byte(`{"login": "octocat", "name": "The Octocat"}`))
+	})
+	u, err := c.GetAuthenticatedUser()
+	require.Nil(t, err)
+	assert.Equal(t, "octocat", u.Login)
+	assert.Equal(t, "The Octocat", u.Name)
+}
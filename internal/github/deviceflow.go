@@ -0,0 +1,185 @@
+// OAuth device authorization flow, for signing in without pasting a token
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth app client ID used for device flow sign-in, if the account doesn't configure one.
+// Empty, as there is no registered OAuth app: sign-in needs the account's oauth_client_id.
+var DefaultOAuthClientID = ""
+
+// Errors returned by the device flow when sign-in can't complete
+var (
+	ErrNoClientID        = errors.New("no OAuth app client ID is configured for sign-in")
+	ErrDeviceCodeExpired = errors.New("the sign-in code expired, please try again")
+	ErrAccessDenied      = errors.New("sign-in was cancelled on Github")
+)
+
+// DeviceFlow performs the OAuth device authorization grant.
+// The endpoint URLs may be changed to point at a test server.
+type DeviceFlow struct {
+	ClientID      string
+	Scopes        []string
+	DeviceCodeURL string // eg: https://github.com/login/device/code
+	TokenURL      string // eg: https://github.com/login/oauth/access_token
+	HTTPClient    *http.Client
+}
+
+// DeviceCode is the code the user enters on Github to approve sign-in
+type DeviceCode struct {
+	DeviceCode      string
+	UserCode        string // the code shown to the user
+	VerificationURI string // the page where the user enters the code
+	ExpiresAt       time.Time
+	Interval        time.Duration // minimum wait between polls
+}
+
+// NewDeviceFlow returns a DeviceFlow for the configured web host, requesting the gist scope
+func (c GithubConfig) NewDeviceFlow() *DeviceFlow {
+	clientID := c.OAuthClientID
+	if clientID == "" {
+		clientID = DefaultOAuthClientID
+	}
+	web := c.WebURL()
+	return &DeviceFlow{
+		ClientID:      clientID,
+		Scopes:        []string{"gist", "read:user"},
+		DeviceCodeURL: web + "/login/device/code",
+		TokenURL:      web + "/login/oauth/access_token",
+		HTTPClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// CanSignIn returns true if an OAuth app client ID is configured for device flow sign-in
+func (c GithubConfig) CanSignIn() bool {
+	return c.OAuthClientID != "" || DefaultOAuthClientID != ""
+}
+
+// RequestCode starts the flow, and returns the code for the user to enter
+func (f *DeviceFlow) RequestCode() (DeviceCode, error) {
+	if f.ClientID == "" {
+		return DeviceCode{}, ErrNoClientID
+	}
+	form := url.Values{
+		"client_id": {f.ClientID},
+		"scope":     {strings.Join(f.Scopes, " ")},
+	}
+	var res struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+		Error           string `json:"error"`
+		ErrorDesc       string `json:"error_description"`
+	}
+	if err := f.post(f.DeviceCodeURL, form, &res); err != nil {
+		return DeviceCode{}, err
+	}
+	if res.Error != "" {
+		return DeviceCode{}, fmt.Errorf("request device code: %s: %s", res.Error, res.ErrorDesc)
+	}
+	interval := res.Interval
+	if interval <= 0 {
+		interval = 5
+	}
+	return DeviceCode{
+		DeviceCode:      res.DeviceCode,
+		UserCode:        res.UserCode,
+		VerificationURI: res.VerificationURI,
+		ExpiresAt:       time.Now().Add(time.Duration(res.ExpiresIn) * time.Second),
+		Interval:        time.Duration(interval) * time.Second,
+	}, nil
+}
+
+// PollToken polls until the user approves or denies sign-in, the code expires,
+// or the context is cancelled. Returns the access token on approval.
+func (f *DeviceFlow) PollToken(ctx context.Context, code DeviceCode) (string, error) {
+	interval := code.Interval
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+		if !code.ExpiresAt.IsZero() && time.Now().After(code.ExpiresAt) {
+			return "", ErrDeviceCodeExpired
+		}
+
+		form := url.Values{
+			"client_id":   {f.ClientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}
+		var res struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			ErrorDesc   string `json:"error_description"`
+			Interval    int    `json:"interval"`
+		}
+		if err := f.post(f.TokenURL, form, &res); err != nil {
+			return "", err
+		}
+		switch res.Error {
+		case "":
+			if res.AccessToken == "" {
+				return "", fmt.Errorf("poll access token: no token returned")
+			}
+			return res.AccessToken, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			if res.Interval > 0 {
+				interval = time.Duration(res.Interval) * time.Second
+			} else {
+				interval += 5 * time.Second
+			}
+		case "expired_token":
+			return "", ErrDeviceCodeExpired
+		case "access_denied":
+			return "", ErrAccessDenied
+		default:
+			return "", fmt.Errorf("poll access token: %s: %s", res.Error, res.ErrorDesc)
+		}
+	}
+}
+
+// post sends a form request, and decodes the JSON response into out
+func (f *DeviceFlow) post(endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	httpClient := f.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST %s failed: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s failed: %d %s", endpoint, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDeviceFlow returns a DeviceFlow using a local stand-in server for the OAuth endpoints
+func newTestDeviceFlow(t *testing.T, h http.HandlerFunc) *DeviceFlow {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	f := GithubConfig{WebBaseURL: srv.URL, OAuthClientID: "test-client"}.NewDeviceFlow()
+	assert.Equal(t, srv.URL+"/login/device/code", f.DeviceCodeURL)
+	return f
+}
+
+func TestDeviceFlow_SignIn(t *testing.T) {
+	polls := 0
+	f := newTestDeviceFlow(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(t, r.ParseForm())
+		assert.Equal(t, "test-client", r.PostForm.Get("client_id"))
+		switch r.URL.Path {
+		case "/login/device/code":
+			assert.Equal(t, "gist read:user", r.PostForm.Get("scope"))
+			w.Write([]byte(`{"device_code":"dev123","user_code":"ABCD-1234","verification_uri":"https://github.com/login/device","expires_in":900,"interval":5}`))
+		case "/login/oauth/access_token":
+			assert.Equal(t, "dev123", r.PostForm.Get("device_code"))
+			polls++
+			if polls < 3 {
+				w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"gho_abc123","token_type":"bearer","scope":"gist"}`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	})
+	code, err := f.RequestCode()
+	require.Nil(t, err)
+	assert.Equal(t, "ABCD-1234", code.UserCode)
+	assert.Equal(t, 5*time.Second, code.Interval)
+
+	code.Interval = time.Millisecond
+	token, err := f.PollToken(context.Background(), code)
+	require.Nil(t, err)
+	assert.Equal(t, "gho_abc123", token)
+	assert.Equal(t, 3, polls)
+}
+
+func TestDeviceFlow_PollErrors(t *testing.T) {
+	for errCode, want := range map[string]error{
+		"access_denied": ErrAccessDenied,
+		"expired_token": ErrDeviceCodeExpired,
+	} {
+		f := newTestDeviceFlow(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"error":"` + errCode + `"}`))
+		})
+		_, err := f.PollToken(context.Background(), DeviceCode{DeviceCode: "dev123", Interval: time.Millisecond})
+		assert.ErrorIs(t, err, want)
+	}
+}
+
+func TestDeviceFlow_PollCancelled(t *testing.T) {
+	f := newTestDeviceFlow(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"authorization_pending"}`))
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := f.PollToken(ctx, DeviceCode{DeviceCode: "dev123", Interval: time.Millisecond})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDeviceFlow_NoClientID(t *testing.T) {
+	host := GithubConfig{}
+	assert.False(t, host.CanSignIn(), "sign-in needs a client ID")
+	_, err := host.NewDeviceFlow().RequestCode()
+	assert.ErrorIs(t, err, ErrNoClientID)
+	assert.True(t, GithubConfig{OAuthClientID: "test-client"}.CanSignIn())
+}
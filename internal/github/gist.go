@@ -2,34 +2,295 @@
 package github
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/avelino/slugify"
 )
 
+// GithubConfig holds the Github host and credentials of an account.
+// Empty URLs default to the public github.com.
 type GithubConfig struct {
+	Name           string // the account name, eg: "work"
 	GithubAPIToken string
+	APIBaseURL     string // base URL of the REST API, eg: https://github.example.com/api/v3
+	WebBaseURL     string // base URL of the web host, used for sign-in and git, eg: https://github.example.com
+	OAuthClientID  string // OAuth app client ID for device flow sign-in. Empty uses DefaultOAuthClientID.
 }
 
-// Example structure for a Github Gist.
-// This is just a placeholder
+// NewClient returns a Github API client for the configured host and token
+func (c GithubConfig) NewClient() *Client {
+	client := NewClient(c.GithubAPIToken)
+	client.BaseURL = c.APIURL()
+	return client
+}
+
+// APIURL returns the base URL of the REST API
+func (c GithubConfig) APIURL() string {
+	if c.APIBaseURL == "" {
+		return DefaultAPIBaseURL
+	}
+	return strings.TrimRight(c.APIBaseURL, "/")
+}
+
+// WebURL returns the base URL of the web host.
+// If not set, it is derived from the API URL.
+func (c GithubConfig) WebURL() string {
+	if c.WebBaseURL != "" {
+		return strings.TrimRight(c.WebBaseURL, "/")
+	}
+	api := c.APIURL()
+	if api == DefaultAPIBaseURL {
+		return DefaultWebBaseURL
+	}
+	return strings.TrimSuffix(api, "/api/v3")
+}
+
+// GistRemoteURL returns the git remote URL of a gist.
+// On Github Enterprise Server, gists are served under the /gist path of the web host.
+func (c GithubConfig) GistRemoteURL(id string) string {
+	if !c.IsEnterprise() {
+		return "https://gist.github.com/" + id + ".git"
+	}
+	return c.WebURL() + "/gist/" + id + ".git"
+}
+
+// IsEnterprise returns true if the config points at a Github Enterprise Server host
+func (c GithubConfig) IsEnterprise() bool {
+	return c.APIURL() != DefaultAPIBaseURL
+}
+
+// Validate checks that the configured URLs are absolute http or https URLs
+func (c GithubConfig) Validate() error {
+	if err := ValidAPIBaseURL(c.APIBaseURL); err != nil {
+		return err
+	}
+	return ValidWebBaseURL(c.WebBaseURL)
+}
+
+// ValidAPIBaseURL checks that an API base URL, if set, is an absolute http or https URL
+func ValidAPIBaseURL(raw string) error {
+	return validURL(raw, "API base URL", "https://github.example.com/api/v3")
+}
+
+// ValidWebBaseURL checks that a web URL, if set, is an absolute http or https URL
+func ValidWebBaseURL(raw string) error {
+	return validURL(raw, "web URL", "https://github.example.com")
+}
+
+// validURL checks that a URL, if set, is an absolute http or https URL.
+// name and example describe the setting in the error.
+func validURL(raw string, name string, example string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an http or https URL, eg: %s", name, example)
+	}
+	return nil
+}
+
+// EnterpriseConfig returns the API and web URLs for a Github Enterprise Server hostname
+func EnterpriseConfig(host string) GithubConfig {
+	return GithubConfig{
+		APIBaseURL: "https://" + host + "/api/v3",
+		WebBaseURL: "https://" + host,
+	}
+}
+
+// Gist represents a Github Gist, made up of one or more files.
+// A Gist with an empty ID has not yet been created on Github.
 type Gist struct {
-	ID        string
-	Slug      string
-	Filename  string
-	Content   string
-	AuthorId  string
-	CreatedAt time.Time
+	ID          string
+	Slug        string
+	Description string
+	Public      bool // public gists are listed on Github; secret gists are only visible by URL
+	Files       []File
+	AuthorId    string // login name of the gist owner
+	URL         string // the gist's page on Github
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Truncated   bool     // true if Github did not return all of the gist's files
+	removed     []string // Github filenames of files removed since the gist was loaded
 }
 
-// Generate a new Gist
+// File is a single file within a Gist
+type File struct {
+	Filename     string
+	Content      string
+	Size         int    // the full size of the file content in bytes, as reported by Github
+	Truncated    bool   // true if Content holds only part of the file; the full content is at RawURL
+	RawURL       string // the URL of the full file content
+	originalName string // the filename on Github when loaded; empty for files not yet saved
+}
+
+// Generate a new secret Gist, containing a single file.
+// The ID is left empty, and is assigned by Github when the gist is created.
 func (g Gist) New(fileName string, content string) Gist {
 	slug := slugify.Slugify(fileName)
 	return Gist{
-		ID:        slug,
 		Slug:      slug,
-		Filename:  fileName,
-		Content:   content,
+		Files:     []File{{Filename: fileName, Content: content}},
 		CreatedAt: time.Now(),
 	}
 }
+
+// IsNew returns true if the gist has not yet been created on Github
+func (g Gist) IsNew() bool { return g.ID == "" }
+
+// Title returns the display title of the gist: the name of its first file
+func (g Gist) Title() string {
+	if len(g.Files) == 0 {
+		return ""
+	}
+	return g.Files[0].Filename
+}
+
+// Filenames returns the names of all files in the gist
+func (g Gist) Filenames() []string {
+	var res []string
+	for _, f := range g.Files {
+		res = append(res, f.Filename)
+	}
+	return res
+}
+
+// FileIndex returns the index of the file with the given name, or -1 if not found
+func (g Gist) FileIndex(name string) int {
+	for i, f := range g.Files {
+		if f.Filename == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// File returns the file with the given name, or nil if not found
+func (g *Gist) File(name string) *File {
+	i := g.FileIndex(name)
+	if i < 0 {
+		return nil
+	}
+	return &g.Files[i]
+}
+
+// PartialFiles returns the names of files whose full content has not been loaded
+func (g Gist) PartialFiles() []string {
+	var res []string
+	for _, f := range g.Files {
+		if f.Truncated {
+			res = append(res, f.Filename)
+		}
+	}
+	return res
+}
+
+// CheckComplete returns an error if the gist was only partially loaded.
+// Saving a partially loaded gist would overwrite files with their truncated content.
+func (g Gist) CheckComplete() error {
+	if g.Truncated {
+		return ErrPartialContent{Reason: "Github did not return all of the gist's files"}
+	}
+	if names := g.PartialFiles(); len(names) > 0 {
+		return ErrPartialContent{Reason: "the full content is not loaded for " + strings.Join(names, ", ")}
+	}
+	return nil
+}
+
+// ErrPartialContent is returned when saving a gist that was only partially loaded
+type ErrPartialContent struct {
+	Reason string
+}
+
+func (e ErrPartialContent) Error() string {
+	return "gist is only partially loaded: " + e.Reason
+}
+
+// AddFile adds a new file to the gist
+func (g *Gist) AddFile(name string, content string) error {
+	if name == "" {
+		return fmt.Errorf("filename is empty")
+	}
+	if g.nameInUse(name, -1) {
+		return fmt.Errorf("a file named %s already exists", name)
+	}
+	g.Files = append(g.Files, File{Filename: name, Content: content})
+	return nil
+}
+
+// RenameFile renames a file in the gist
+func (g *Gist) RenameFile(oldName string, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("filename is empty")
+	}
+	i := g.FileIndex(oldName)
+	if i < 0 {
+		return fmt.Errorf("file not found: %s", oldName)
+	}
+	if g.nameInUse(newName, i) {
+		return fmt.Errorf("a file named %s already exists", newName)
+	}
+	g.Files[i].Filename = newName
+	return nil
+}
+
+// nameInUse returns true if any file other than the one at index skip has the
+// given name, either currently or on Github.
+// Names still in use on Github can't be reused until the gist is saved.
+func (g Gist) nameInUse(name string, skip int) bool {
+	for i, f := range g.Files {
+		if i != skip && (f.Filename == name || f.originalName == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveFile removes a file from the gist. A gist must contain at least one file.
+func (g *Gist) RemoveFile(name string) error {
+	i := g.FileIndex(name)
+	if i < 0 {
+		return fmt.Errorf("file not found: %s", name)
+	}
+	if len(g.Files) == 1 {
+		return fmt.Errorf("a gist must contain at least one file")
+	}
+	if orig := g.Files[i].originalName; orig != "" {
+		g.removed = append(g.removed, orig)
+	}
+	files := make([]File, 0, len(g.Files)-1)
+	files = append(files, g.Files[:i]...)
+	g.Files = append(files, g.Files[i+1:]...)
+	return nil
+}
+
+// WithFilesOf returns a copy of the gist with its files replaced by those of
+// another version of it, such as an older revision.
+// Files that are not in the other version are removed when the gist is saved.
+func (g Gist) WithFilesOf(other Gist) Gist {
+	res := g
+	res.Files = nil
+	res.Truncated = other.Truncated
+	res.removed = append([]string{}, g.removed...)
+	onGithub := map[string]bool{}
+	for _, f := range g.Files {
+		if f.originalName == "" {
+			continue
+		}
+		onGithub[f.originalName] = true
+		if other.FileIndex(f.originalName) < 0 {
+			res.removed = append(res.removed, f.originalName)
+		}
+	}
+	for _, f := range other.Files {
+		x := File{Filename: f.Filename, Content: f.Content, Size: f.Size, Truncated: f.Truncated, RawURL: f.RawURL}
+		if onGithub[f.Filename] {
+			x.originalName = f.Filename
+		}
+		res.Files = append(res.Files, x)
+	}
+	return res
+}
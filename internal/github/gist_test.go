@@ -0,0 +1,92 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGist_FileOperations(t *testing.T) {
+	g := Gist{}.New("README.md", "# Readme")
+	assert.Equal(t, "README.md", g.Title())
+
+	// Add
+	assert.Nil(t, g.AddFile("script.sh", "echo hi"))
+	assert.NotNil(t, g.AddFile("script.sh", ""), "duplicate filenames should be rejected")
+	assert.NotNil(t, g.AddFile("", ""), "empty filenames should be rejected")
+	assert.Equal(t, []string{"README.md", "script.sh"}, g.Filenames())
+
+	// Rename
+	assert.Nil(t, g.RenameFile("script.sh", "run.sh"))
+	assert.NotNil(t, g.RenameFile("run.sh", "README.md"), "renaming to an existing filename should be rejected")
+	assert.NotNil(t, g.RenameFile("missing.sh", "other.sh"))
+	assert.Equal(t, "echo hi", g.File("run.sh").Content)
+
+	// Remove
+	assert.Nil(t, g.RemoveFile("run.sh"))
+	assert.Nil(t, g.File("run.sh"))
+	assert.NotNil(t, g.RemoveFile("README.md"), "the last file can't be removed")
+	assert.Empty(t, g.removed, "unsaved files should not be sent as removed")
+}
+
+func TestGist_RenameKeepsGithubName(t *testing.T) {
+	g := Gist{ID: "abc", Files: []File{
+		{Filename: "a.md", originalName: "a.md"},
+		{Filename: "b.md", originalName: "b.md"},
+	}}
+	assert.Nil(t, g.RenameFile("a.md", "c.md"))
+	assert.NotNil(t, g.AddFile("a.md", ""), "a name still in use on Github can't be reused before saving")
+
+	assert.Nil(t, g.RemoveFile("b.md"))
+	assert.Equal(t, []string{"b.md"}, g.removed)
+	assert.Nil(t, g.AddFile("b.md", "replaced"), "a removed name can be reused")
+	assert.Equal(t, &apiFile{Content: "replaced"}, updatedFiles(g)["b.md"])
+}
+
+func TestGist_WithFilesOf(t *testing.T) {
+	current := Gist{ID: "abc", Files: []File{
+		{Filename: "README.md", Content: "new", originalName: "README.md"},
+		{Filename: "added.sh", Content: "echo", originalName: "added.sh"},
+	}}
+	revision := Gist{ID: "abc", Files: []File{
+		{Filename: "README.md", Content: "old", originalName: "README.md"},
+		{Filename: "deleted.sh", Content: "echo old", originalName: "deleted.sh"},
+	}}
+	restored := current.WithFilesOf(revision)
+	assert.Equal(t, "abc", restored.ID)
+	assert.Equal(t, []string{"README.md", "deleted.sh"}, restored.Filenames())
+
+	files := updatedFiles(restored)
+	assert.Equal(t, &apiFile{Content: "old"}, files["README.md"])
+	assert.Equal(t, &apiFile{Content: "echo old"}, files["deleted.sh"])
+	v, ok := files["added.sh"]
+	assert.True(t, ok && v == nil, "files not in the revision should be removed")
+	assert.Equal(t, 2, len(current.Files), "the original gist should not be changed")
+}
+
+func TestGithubConfig_URLs(t *testing.T) {
+	c := GithubConfig{}
+	assert.Equal(t, DefaultAPIBaseURL, c.APIURL())
+	assert.Equal(t, DefaultWebBaseURL, c.WebURL())
+	assert.False(t, c.IsEnterprise())
+	assert.Equal(t, "https://gist.github.com/abc123.git", c.GistRemoteURL("abc123"))
+
+	c = GithubConfig{APIBaseURL: "https://github.example.com/api/v3/"}
+	assert.Equal(t, "https://github.example.com/api/v3", c.APIURL())
+	assert.Equal(t, "https://github.example.com", c.WebURL(), "web URL should be derived from the API URL")
+	assert.True(t, c.IsEnterprise())
+	assert.Equal(t, "https://github.example.com/api/v3", c.NewClient().BaseURL)
+	assert.Equal(t, "https://github.example.com/gist/abc123.git", c.GistRemoteURL("abc123"))
+
+	assert.Equal(t, GithubConfig{
+		APIBaseURL: "https://github.example.com/api/v3",
+		WebBaseURL: "https://github.example.com",
+	}, EnterpriseConfig("github.example.com"))
+}
+
+func TestGithubConfig_Validate(t *testing.T) {
+	assert.Nil(t, GithubConfig{}.Validate())
+	assert.Nil(t, EnterpriseConfig("github.example.com").Validate())
+	assert.NotNil(t, GithubConfig{APIBaseURL: "github.example.com"}.Validate(), "URL without scheme should be rejected")
+	assert.NotNil(t, GithubConfig{WebBaseURL: "ftp://github.example.com"}.Validate())
+}
@@ -0,0 +1,86 @@
+// Gist revision history
+package github
+
+import (
+	"net/http"
+	"time"
+)
+
+// Revision is a single version in a gist's history
+type Revision struct {
+	Version     string // the commit SHA of the revision
+	Author      string // login name of the user who made the change
+	CommittedAt time.Time
+	Additions   int
+	Deletions   int
+}
+
+// ShortVersion returns the abbreviated commit SHA of the revision
+func (r Revision) ShortVersion() string {
+	if len(r.Version) > 7 {
+		return r.Version[:7]
+	}
+	return r.Version
+}
+
+// ListRevisions returns the revision history of a gist, newest first. Every page is fetched.
+func (c *Client) ListRevisions(id string) ([]Revision, error) {
+	var res []apiCommit
+	err := c.eachPage("/gists/"+id+"/commits", ListOptions{}, func(resp *response) error {
+		var page []apiCommit
+		if err := decodeResponse(resp, &page); err != nil {
+			return err
+		}
+		res = append(res, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, 0, len(res))
+	for _, x := range res {
+		r := Revision{
+			Version:     x.Version,
+			CommittedAt: x.CommittedAt,
+			Additions:   x.ChangeStatus.Additions,
+			Deletions:   x.ChangeStatus.Deletions,
+		}
+		if x.User != nil {
+			r.Author = x.User.Login
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+// GetRevision returns a gist as it was at the given revision
+func (c *Client) GetRevision(id string, version string) (Gist, error) {
+	var res apiGist
+	if err := c.do(http.MethodGet, "/gists/"+id+"/"+version, nil, &res); err != nil {
+		return Gist{}, err
+	}
+	return res.toGist(), nil
+}
+
+// RestoreRevision replaces the files of a gist with those of an older revision,
+// saved as a new revision. Truncated files of the revision are downloaded in full first.
+// Returns the gist as stored on Github.
+func (c *Client) RestoreRevision(current Gist, revision Gist) (Gist, error) {
+	for i := range revision.Files {
+		if err := c.LoadFile(&revision.Files[i], nil); err != nil {
+			return Gist{}, err
+		}
+	}
+	return c.UpdateGist(current.WithFilesOf(revision))
+}
+
+// apiCommit is the JSON representation of a gist commit
+type apiCommit struct {
+	Version      string    `json:"version"`
+	User         *apiUser  `json:"user"`
+	CommittedAt  time.Time `json:"committed_at"`
+	ChangeStatus struct {
+		Additions int `json:"additions"`
+		Deletions int `json:"deletions"`
+	} `json:"change_status"`
+}
@@ -0,0 +1,56 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListRevisions(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists/abc123/commits", r.URL.Path)
+		w.Write([]byte(`[{
+			"version": "57a7f021a713b1c5a6a199b54cc514735d2d462f",
+			"user": {"login": "octocat"},
+			"committed_at": "2023-01-02T03:04:05Z",
+			"change_status": {"additions": 3, "deletions": 1, "total": 4}
+		}]`))
+	})
+	res, err := c.ListRevisions("abc123")
+	require.Nil(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "57a7f02", res[0].ShortVersion())
+	assert.Equal(t, "octocat", res[0].Author)
+	assert.Equal(t, 3, res[0].Additions)
+	assert.Equal(t, 1, res[0].Deletions)
+}
+
+func TestClient_ListRevisionsPages(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/gists/abc123/commits?page=2>; rel="next"`, srvURL))
+		}
+		w.Write([]byte(fmt.Sprintf(`[{"version": "v%s"}]`, page)))
+	})
+	srvURL = c.BaseURL
+	res, err := c.ListRevisions("abc123")
+	require.Nil(t, err)
+	require.Len(t, res, 2, "all pages should be fetched")
+	assert.Equal(t, "v2", res[1].Version)
+}
+
+func TestClient_GetRevision(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists/abc123/57a7f02", r.URL.Path)
+		w.Write([]byte(exampleGistJSON))
+	})
+	g, err := c.GetRevision("abc123", "57a7f02")
+	require.Nil(t, err)
+	assert.Equal(t, "# Hello", g.Files[0].Content)
+}
@@ -7,10 +7,12 @@ import (
 )
 
 var ExampleGist = Gist{
-	ID:        "example-gist",
-	Slug:      "example-gist",
-	Filename:  "Example Gist.md",
-	Content:   "## Example Gist\n\nThis is an example Gist placeholder.\n\nA list:\n- item 1\n- item 2\n- item 3",
+	ID:   "example-gist",
+	Slug: "example-gist",
+	Files: []File{{
+		Filename: "Example Gist.md",
+		Content:  "## Example Gist\n\nThis is an example Gist placeholder.\n\nA list:\n- item 1\n- item 2\n- item 3",
+	}},
 	AuthorId:  "example-author",
 	CreatedAt: time.Now(),
 }
@@ -18,8 +20,10 @@ var ExampleGist = Gist{
 func newExampleGist(id int) Gist {
 	e := ExampleGist
 	e.ID = fmt.Sprintf("example-gist-%d", id)
-	e.Filename = fmt.Sprintf("Example Gist-%d", id)
-	e.Content = e.Content + " -- gist ID: " + e.ID
+	e.Files = []File{{
+		Filename: fmt.Sprintf("Example Gist-%d", id),
+		Content:  ExampleGist.Files[0].Content + " -- gist ID: " + e.ID,
+	}}
 	return e
 }
 
@@ -0,0 +1,152 @@
+// Paginated and incremental gist listing
+package github
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Maximum number of results per page allowed by the API
+const MaxPerPage = 100
+
+// ListOptions are the paging and filtering options for listing gists
+type ListOptions struct {
+	Page    int       // the page to fetch, starting from 1
+	PerPage int       // results per page, up to MaxPerPage
+	Since   time.Time // if set, only gists updated at or after this time are listed
+}
+
+// GistPage is a single page of a gist listing
+type GistPage struct {
+	Gists    []Gist
+	NextPage int // the next page number, or 0 if this is the last page
+}
+
+// query returns the options as URL query parameters
+func (o ListOptions) query() string {
+	v := url.Values{}
+	if o.Page > 0 {
+		v.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PerPage > 0 {
+		v.Set("per_page", strconv.Itoa(o.PerPage))
+	}
+	if !o.Since.IsZero() {
+		v.Set("since", o.Since.UTC().Format(time.RFC3339))
+	}
+	if len(v) == 0 {
+		return ""
+	}
+	return "?" + v.Encode()
+}
+
+// listGistsPage fetches a page of gists from a list endpoint
+func (c *Client) listGistsPage(path string, opts ListOptions) (GistPage, error) {
+	resp, err := c.send(http.MethodGet, path+opts.query(), nil)
+	if err != nil {
+		return GistPage{}, err
+	}
+	var res []apiGist
+	if err := decodeResponse(resp, &res); err != nil {
+		return GistPage{}, err
+	}
+	return GistPage{
+		Gists:    toGists(res),
+		NextPage: nextPage(resp.Header.Get("Link")),
+	}, nil
+}
+
+// listAllPages fetches every page of gists from a list endpoint
+func (c *Client) listAllPages(path string, opts ListOptions) ([]Gist, error) {
+	var all []Gist
+	err := c.eachPage(path, opts, func(resp *response) error {
+		var res []apiGist
+		if err := decodeResponse(resp, &res); err != nil {
+			return err
+		}
+		all = append(all, toGists(res)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// eachPage fetches every page of a list endpoint, following the Link header,
+// and calls fn with each response
+func (c *Client) eachPage(path string, opts ListOptions, fn func(resp *response) error) error {
+	if opts.PerPage == 0 {
+		opts.PerPage = MaxPerPage
+	}
+	for page := 1; page > 0; {
+		opts.Page = page
+		resp, err := c.send(http.MethodGet, path+opts.query(), nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(resp); err != nil {
+			return err
+		}
+		page = nextPage(resp.Header.Get("Link"))
+	}
+	return nil
+}
+
+// The next page link in a Link header, eg: <https://api.github.com/gists?page=2>; rel="next"
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage returns the next page number from a Link response header, or 0 if there is none
+func nextPage(link string) int {
+	m := nextLinkRegex.FindStringSubmatch(link)
+	if m == nil {
+		return 0
+	}
+	u, err := url.Parse(m[1])
+	if err != nil {
+		return 0
+	}
+	page, err := strconv.Atoi(u.Query().Get("page"))
+	if err != nil {
+		return 0
+	}
+	return page
+}
+
+// MergeGists merges updated gists into a list of gists, replacing gists with
+// the same ID and adding new ones. The result is sorted by most recently updated.
+func MergeGists(existing []Gist, updates []Gist) []Gist {
+	byID := map[string]int{}
+	res := append([]Gist{}, existing...)
+	for i, g := range res {
+		byID[g.ID] = i
+	}
+	for _, g := range updates {
+		if i, ok := byID[g.ID]; ok {
+			res[i] = g
+			continue
+		}
+		byID[g.ID] = len(res)
+		res = append(res, g)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].UpdatedAt.After(res[j].UpdatedAt)
+	})
+	return res
+}
+
+// LatestUpdate returns the most recent UpdatedAt time of the gists, to use as
+// the Since option of an incremental listing. Returns a zero time for an empty list.
+func LatestUpdate(gists []Gist) time.Time {
+	var latest time.Time
+	for _, g := range gists {
+		if g.UpdatedAt.After(latest) {
+			latest = g.UpdatedAt
+		}
+	}
+	return latest
+}
@@ -0,0 +1,84 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextPage(t *testing.T) {
+	link := `<https://api.github.com/gists?page=3&per_page=30>; rel="next", <https://api.github.com/gists?page=10&per_page=30>; rel="last"`
+	assert.Equal(t, 3, nextPage(link))
+	assert.Equal(t, 0, nextPage(`<https://api.github.com/gists?page=1>; rel="prev"`), "no next link")
+	assert.Equal(t, 0, nextPage(""))
+}
+
+func TestListOptions_query(t *testing.T) {
+	assert.Equal(t, "", ListOptions{}.query())
+	since := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts := ListOptions{Page: 2, PerPage: 50, Since: since}
+	assert.Equal(t, "?page=2&per_page=50&since=2023-01-02T03%3A04%3A05Z", opts.query())
+}
+
+func TestClient_ListGistsPages(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/gists?page=2>; rel="next"`, srvURL))
+		}
+		w.Write([]byte(fmt.Sprintf(`[{"id": "gist-%s", "files": {}}]`, page)))
+	})
+	srvURL = c.BaseURL
+
+	first, err := c.ListGists(ListOptions{Page: 1})
+	require.Nil(t, err)
+	assert.Equal(t, "gist-1", first.Gists[0].ID)
+	assert.Equal(t, 2, first.NextPage)
+
+	second, err := c.ListGists(ListOptions{Page: first.NextPage})
+	require.Nil(t, err)
+	assert.Equal(t, "gist-2", second.Gists[0].ID)
+	assert.Equal(t, 0, second.NextPage)
+}
+
+func TestClient_ListUpdatedGists(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2023-01-02T03:04:05Z", r.URL.Query().Get("since"))
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/gists?page=2>; rel="next"`, srvURL))
+		}
+		w.Write([]byte(fmt.Sprintf(`[{"id": "gist-%s", "files": {}}]`, page)))
+	})
+	srvURL = c.BaseURL
+	res, err := c.ListUpdatedGists(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.Nil(t, err)
+	require.Len(t, res, 2, "all pages should be fetched")
+}
+
+func TestMergeGists(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC) }
+	existing := []Gist{
+		{ID: "b", UpdatedAt: day(2)},
+		{ID: "a", UpdatedAt: day(1)},
+	}
+	updates := []Gist{
+		{ID: "a", UpdatedAt: day(4), Slug: "updated"},
+		{ID: "c", UpdatedAt: day(3)},
+	}
+	res := MergeGists(existing, updates)
+	var ids []string
+	for _, g := range res {
+		ids = append(ids, g.ID)
+	}
+	assert.Equal(t, []string{"a", "c", "b"}, ids, "merged gists should be sorted by most recently updated")
+	assert.Equal(t, "updated", res[0].Slug, "updated gists should be replaced")
+	assert.Equal(t, day(4), LatestUpdate(res))
+	assert.True(t, LatestUpdate(nil).IsZero())
+}
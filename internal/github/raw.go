@@ -0,0 +1,92 @@
+// Downloading the full content of truncated and large gist files
+package github
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Size of the chunks read between progress updates
+const rawChunkSize = 32 * 1024
+
+// LoadFile downloads the full content of a truncated file from its raw URL,
+// and stores it to the file. Files that are not truncated are left unchanged.
+// progress, if given, is called as the content is read, with the bytes read so far
+// and the expected total, which is 0 if unknown.
+func (c *Client) LoadFile(f *File, progress func(read int, total int)) error {
+	if !f.Truncated {
+		return nil
+	}
+	if f.RawURL == "" {
+		return fmt.Errorf("load %s: file has no raw URL", f.Filename)
+	}
+	content, err := c.GetRawContent(f.RawURL, f.Size, progress)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", f.Filename, err)
+	}
+	f.Content = content
+	f.Size = len(content)
+	f.Truncated = false
+	return nil
+}
+
+// GetRawContent downloads the content at a gist file's raw URL.
+// The token is only sent if the URL is on the API host, as raw content is
+// served from a separate host on github.com.
+func (c *Client) GetRawContent(rawURL string, size int, progress func(read int, total int)) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	if c.Token != "" && sameHost(rawURL, c.BaseURL) {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GET %s failed: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", &APIError{StatusCode: resp.StatusCode}
+	}
+	if size <= 0 && resp.ContentLength > 0 {
+		size = int(resp.ContentLength)
+	}
+
+	var buf bytes.Buffer
+	chunk := make([]byte, rawChunkSize)
+	for {
+		n, err := resp.Body.Read(chunk)
+		buf.Write(chunk[:n])
+		if progress != nil && n > 0 {
+			progress(buf.Len(), size)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("read raw content: %w", err)
+		}
+	}
+	return buf.String(), nil
+}
+
+// sameHost returns true if both URLs have the same host
+func sameHost(a string, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host == ub.Host
+}
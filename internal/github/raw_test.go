@@ -0,0 +1,52 @@
+package github
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_LoadTruncatedFile(t *testing.T) {
+	full := strings.Repeat("x", 100000)
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gists/abc123":
+			w.Write([]byte(`{"id": "abc123", "files": {
+				"big.txt": {"filename": "big.txt", "content": "xxx", "size": 100000, "truncated": true, "raw_url": "` + srvURL + `/raw/big.txt"},
+				"huge.bin": {"filename": "huge.bin", "size": 20000000, "raw_url": "` + srvURL + `/raw/huge.bin"}
+			}}`))
+		case "/raw/big.txt":
+			assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"), "token should be sent to the API host")
+			w.Write([]byte(full))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	})
+	srvURL = c.BaseURL
+
+	g, err := c.GetGist("abc123")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"big.txt", "huge.bin"}, g.PartialFiles(), "files without inline content should be partial")
+
+	// Saving a partially loaded gist is refused
+	_, err = c.UpdateGist(g)
+	assert.ErrorAs(t, err, &ErrPartialContent{})
+
+	var lastRead, lastTotal int
+	f := g.File("big.txt")
+	require.Nil(t, c.LoadFile(f, func(read, total int) { lastRead, lastTotal = read, total }))
+	assert.Equal(t, full, f.Content)
+	assert.False(t, f.Truncated)
+	assert.Equal(t, 100000, lastRead)
+	assert.Equal(t, 100000, lastTotal)
+	assert.Equal(t, []string{"huge.bin"}, g.PartialFiles())
+}
+
+func Test_sameHost(t *testing.T) {
+	assert.True(t, sameHost("https://github.example.com/raw/x", "https://github.example.com/api/v3"))
+	assert.False(t, sameHost("https://gist.githubusercontent.com/raw/x", DefaultAPIBaseURL))
+}
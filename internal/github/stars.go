@@ -0,0 +1,56 @@
+// Starred gists and forks
+package github
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// StarGist stars a gist for the authenticated user
+func (c *Client) StarGist(id string) error {
+	return c.do(http.MethodPut, "/gists/"+id+"/star", nil, nil)
+}
+
+// UnstarGist removes the star from a gist for the authenticated user
+func (c *Client) UnstarGist(id string) error {
+	return c.do(http.MethodDelete, "/gists/"+id+"/star", nil, nil)
+}
+
+// IsStarred returns true if the authenticated user has starred the gist
+func (c *Client) IsStarred(id string) (bool, error) {
+	err := c.do(http.MethodGet, "/gists/"+id+"/star", nil, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListStarred returns a page of the gists starred by the authenticated user
+func (c *Client) ListStarred(opts ListOptions) (GistPage, error) {
+	return c.listGistsPage("/gists/starred", opts)
+}
+
+// ListUpdatedStarred returns all starred gists updated since the given time
+func (c *Client) ListUpdatedStarred(since time.Time) ([]Gist, error) {
+	return c.listAllPages("/gists/starred", ListOptions{Since: since})
+}
+
+// ForkGist copies another user's gist into the authenticated user's account,
+// and returns the new fork
+func (c *Client) ForkGist(id string) (Gist, error) {
+	var res apiGist
+	if err := c.do(http.MethodPost, "/gists/"+id+"/forks", nil, &res); err != nil {
+		return Gist{}, err
+	}
+	return res.toGist(), nil
+}
+
+// ListForks returns the forks of a gist. Every page is fetched.
+func (c *Client) ListForks(id string) ([]Gist, error) {
+	return c.listAllPages("/gists/"+id+"/forks", ListOptions{})
+}
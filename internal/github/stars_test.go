@@ -0,0 +1,80 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_StarUnstar(t *testing.T) {
+	starred := false
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists/abc123/star", r.URL.Path)
+		switch r.Method {
+		case http.MethodPut:
+			starred = true
+		case http.MethodDelete:
+			starred = false
+		case http.MethodGet:
+			if !starred {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ok, err := c.IsStarred("abc123")
+	require.Nil(t, err)
+	assert.False(t, ok)
+
+	require.Nil(t, c.StarGist("abc123"))
+	ok, err = c.IsStarred("abc123")
+	require.Nil(t, err)
+	assert.True(t, ok)
+
+	require.Nil(t, c.UnstarGist("abc123"))
+	ok, _ = c.IsStarred("abc123")
+	assert.False(t, ok)
+}
+
+func TestClient_ListStarred(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists/starred", r.URL.Path)
+		w.Write([]byte("[" + exampleGistJSON + "]"))
+	})
+	res, err := c.ListStarred(ListOptions{})
+	require.Nil(t, err)
+	assert.Len(t, res.Gists, 1)
+}
+
+func TestClient_ForkGist(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/gists/other/forks", r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(exampleGistJSON))
+	})
+	fork, err := c.ForkGist("other")
+	require.Nil(t, err)
+	assert.Equal(t, "abc123", fork.ID)
+}
+
+func TestClient_ListForks(t *testing.T) {
+	var srvURL string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/gists/other/forks", r.URL.Path)
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/gists/other/forks?page=2>; rel="next"`, srvURL))
+		}
+		w.Write([]byte("[" + exampleGistJSON + "]"))
+	})
+	srvURL = c.BaseURL
+	forks, err := c.ListForks("other")
+	require.Nil(t, err)
+	require.Len(t, forks, 2, "all pages should be fetched")
+	assert.Equal(t, "octocat", forks[0].AuthorId)
+}
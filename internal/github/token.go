@@ -0,0 +1,80 @@
+// Token verification: identity and OAuth scope checks
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Scope required to read and write gists
+const GistScope = "gist"
+
+// Errors returned when verifying a token
+var (
+	ErrTokenInvalid = errors.New("the token is invalid, expired or has been revoked")
+	ErrMissingScope = errors.New("the token does not have the 'gist' scope")
+)
+
+// TokenInfo describes the user and permissions of an API token
+type TokenInfo struct {
+	User   User
+	Scopes []string // the OAuth scopes granted. Empty for fine-grained tokens, which don't report scopes.
+	scoped bool     // true if the API reported the scopes
+}
+
+// HasScope returns true if the token has the given OAuth scope.
+// Always true for tokens whose scopes are not reported.
+func (t TokenInfo) HasScope(scope string) bool {
+	if !t.scoped {
+		return true
+	}
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyToken checks the client's token with the authenticated user endpoint.
+// Returns ErrTokenInvalid if the token is rejected, and ErrMissingScope if it
+// can't be used for gists.
+func (c *Client) VerifyToken() (TokenInfo, error) {
+	if c.Token == "" {
+		return TokenInfo{}, fmt.Errorf("token is empty")
+	}
+	resp, err := c.send(http.MethodGet, "/user", nil)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return TokenInfo{}, ErrTokenInvalid
+		}
+		return TokenInfo{}, err
+	}
+	var res apiUser
+	if err := decodeResponse(resp, &res); err != nil {
+		return TokenInfo{}, err
+	}
+	info := TokenInfo{User: User{Login: res.Login, Name: res.Name}}
+	if _, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		info.scoped = true
+		info.Scopes = parseScopes(resp.Header.Get("X-OAuth-Scopes"))
+	}
+	if !info.HasScope(GistScope) {
+		return info, ErrMissingScope
+	}
+	return info, nil
+}
+
+// parseScopes parses a comma separated X-OAuth-Scopes header
+func parseScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,49 @@
+package github
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_VerifyToken(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/user", r.URL.Path)
+		w.Header().Set("X-OAuth-Scopes", "gist, read:user")
+		w.Write([]byte(`{"login":"octocat","name":"The Octocat"}`))
+	})
+	info, err := c.VerifyToken()
+	require.Nil(t, err)
+	assert.Equal(t, "octocat", info.User.Login)
+	assert.Equal(t, []string{"gist", "read:user"}, info.Scopes)
+}
+
+func TestClient_VerifyToken_MissingScope(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-OAuth-Scopes", "repo")
+		w.Write([]byte(`{"login":"octocat"}`))
+	})
+	info, err := c.VerifyToken()
+	assert.ErrorIs(t, err, ErrMissingScope)
+	assert.Equal(t, "octocat", info.User.Login)
+}
+
+func TestClient_VerifyToken_FineGrained(t *testing.T) {
+	// Fine-grained tokens don't report scopes
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"login":"octocat"}`))
+	})
+	_, err := c.VerifyToken()
+	assert.Nil(t, err)
+}
+
+func TestClient_VerifyToken_Revoked(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"Bad credentials"}`))
+	})
+	_, err := c.VerifyToken()
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+}
@@ -0,0 +1,268 @@
+// HTTP transport for the API client: conditional requests, response caching,
+// rate limit tracking and retries after secondary rate limits
+package github
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// Wait before retrying after a secondary rate limit, if the response has no Retry-After header.
+// Doubled on each further retry.
+var secondaryRateLimitWait = time.Minute
+
+// Maximum number of cached GET responses. The least recently used are dropped first.
+var maxCacheEntries = 200
+
+// RateLimit is the API rate limit status, from the X-RateLimit-* response headers
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time // when the remaining requests are reset
+	Resource  string    // the rate limit bucket, eg: "core"
+}
+
+// IsKnown returns true if the rate limit status has been received from the API
+func (r RateLimit) IsKnown() bool { return r.Limit > 0 }
+
+// cacheEntry is a cached GET response, with its validators for conditional requests.
+// The response headers are kept, as a 304 response need not repeat them, eg: Link.
+type cacheEntry struct {
+	url          string
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// response is a completed API response
+type response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// RateLimit returns the most recently received rate limit status
+func (c *Client) RateLimit() RateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rateLimit
+}
+
+// do sends a request to the API, and decodes the JSON response into out, if given.
+// Non-2xx responses are returned as an *APIError.
+func (c *Client) do(method string, path string, body interface{}, out interface{}) error {
+	resp, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+// send sends a request to the API, and returns the response.
+// GET requests are sent as conditional requests if the response is cached, and
+// requests that hit a secondary rate limit are retried after waiting.
+func (c *Client) send(method string, path string, body interface{}) (*response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request body: %w", err)
+		}
+	}
+	url := strings.TrimRight(c.BaseURL, "/") + path
+
+	var cached *cacheEntry
+	if method == http.MethodGet {
+		cached = c.cached(url)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.roundTrip(method, url, data, cached)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s failed: %w", method, path, err)
+		}
+		c.updateRateLimit(resp.Header)
+
+		if wait, ok := retryWait(resp, attempt); ok && attempt < c.MaxRetries {
+			logger.Warn("secondary rate limit hit: retrying %s %s in %s", method, path, wait)
+			if c.sleep != nil {
+				c.sleep(wait)
+			}
+			continue
+		}
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
+			resp.StatusCode = http.StatusOK
+			resp.Header = cached.header
+			resp.Body = cached.body
+			return resp, nil
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			apiErr := &APIError{StatusCode: resp.StatusCode}
+			json.Unmarshal(resp.Body, apiErr) // the message is optional, so ignore decoding errors
+			return nil, apiErr
+		}
+		if method == http.MethodGet {
+			c.storeCache(url, resp)
+		}
+		return resp, nil
+	}
+}
+
+// roundTrip sends a single HTTP request, adding the conditional request headers
+// of the cached response, if given
+func (c *Client) roundTrip(method string, url string, data []byte, cached *cacheEntry) (*response, error) {
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	return &response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// cached returns the cached response for a URL, or nil if there is none
+func (c *Client) cached(url string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.cache[url]
+	if !ok {
+		return nil
+	}
+	c.cacheOrder.MoveToFront(el)
+	return el.Value.(*cacheEntry)
+}
+
+// storeCache caches a GET response, if it has an ETag or Last-Modified validator.
+// The least recently used responses are dropped to keep at most maxCacheEntries.
+func (c *Client) storeCache(url string, resp *response) {
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return
+	}
+	e := &cacheEntry{url: url, etag: etag, lastModified: lastModified, header: resp.Header, body: resp.Body}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil {
+		c.cache = map[string]*list.Element{}
+	}
+	if c.cacheOrder == nil {
+		c.cacheOrder = list.New()
+	}
+	if el, ok := c.cache[url]; ok {
+		el.Value = e
+		c.cacheOrder.MoveToFront(el)
+		return
+	}
+	c.cache[url] = c.cacheOrder.PushFront(e)
+	for c.cacheOrder.Len() > maxCacheEntries {
+		oldest := c.cacheOrder.Back()
+		c.cacheOrder.Remove(oldest)
+		delete(c.cache, oldest.Value.(*cacheEntry).url)
+	}
+}
+
+// updateRateLimit stores the rate limit status from the response headers, if present
+func (c *Client) updateRateLimit(h http.Header) {
+	r, ok := parseRateLimit(h)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	c.rateLimit = r
+	c.mu.Unlock()
+	if c.OnRateLimit != nil {
+		c.OnRateLimit(r)
+	}
+}
+
+// parseRateLimit parses the X-RateLimit-* headers.
+// Returns false if the headers are not present.
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	r := RateLimit{Limit: limit, Resource: h.Get("X-RateLimit-Resource")}
+	r.Remaining, _ = strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	r.Used, _ = strconv.Atoi(h.Get("X-RateLimit-Used"))
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		r.Reset = time.Unix(reset, 0)
+	}
+	return r, true
+}
+
+// retryWait returns how long to wait before retrying a request that hit a
+// secondary rate limit, and false if the response is not a secondary rate limit.
+// An exhausted primary rate limit is not retried, as it may take up to an hour to reset.
+func retryWait(resp *response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return 0, false
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || strings.Contains(strings.ToLower(string(resp.Body)), "secondary rate limit") {
+		return secondaryRateLimitWait << attempt, true
+	}
+	return 0, false
+}
+
+// decodeResponse decodes a JSON response body into out, if given
+func decodeResponse(resp *response, out interface{}) error {
+	if out == nil || len(resp.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Body, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ConditionalRequests(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(exampleGistJSON))
+	})
+	g, err := c.GetGist("abc123")
+	require.Nil(t, err)
+	assert.Equal(t, "# Hello", g.Files[0].Content)
+
+	// The second request is conditional, and the cached response is used
+	g, err = c.GetGist("abc123")
+	require.Nil(t, err)
+	assert.Equal(t, "# Hello", g.Files[0].Content, "cached content should be returned on 304")
+	assert.Equal(t, 2, requests)
+}
+
+func TestClient_LastModified(t *testing.T) {
+	lastModified := "Mon, 02 Jan 2023 03:04:05 GMT"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte("[" + exampleGistJSON + "]"))
+	})
+	_, err := c.ListGists(ListOptions{})
+	require.Nil(t, err)
+	res, err := c.ListGists(ListOptions{})
+	require.Nil(t, err)
+	assert.Len(t, res.Gists, 1)
+}
+
+func TestClient_NotModifiedKeepsLink(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified) // without the Link header
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Link", `<https://api.github.com/gists?page=2>; rel="next"`)
+		w.Write([]byte("[" + exampleGistJSON + "]"))
+	})
+	_, err := c.ListGists(ListOptions{Page: 1})
+	require.Nil(t, err)
+	res, err := c.ListGists(ListOptions{Page: 1})
+	require.Nil(t, err)
+	assert.Equal(t, 2, res.NextPage, "the cached Link header should be used on 304")
+}
+
+func TestClient_CacheLimit(t *testing.T) {
+	defer func(n int) { maxCacheEntries = n }(maxCacheEntries)
+	maxCacheEntries = 2
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(exampleGistJSON))
+	})
+	for _, id := range []string{"a", "b", "a", "c"} {
+		_, err := c.GetGist(id)
+		require.Nil(t, err)
+	}
+	assert.Len(t, c.cache, 2)
+	assert.NotNil(t, c.cached(c.BaseURL+"/gists/a"), "recently used responses should be kept")
+	assert.Nil(t, c.cached(c.BaseURL+"/gists/b"), "the least recently used response should be dropped")
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	var notified RateLimit
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Used", "10")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		w.Header().Set("X-RateLimit-Resource", "core")
+		w.Write([]byte("[]"))
+	})
+	c.OnRateLimit = func(r RateLimit) { notified = r }
+	assert.False(t, c.RateLimit().IsKnown())
+
+	_, err := c.ListGists(ListOptions{})
+	require.Nil(t, err)
+	r := c.RateLimit()
+	assert.True(t, r.IsKnown())
+	assert.Equal(t, 5000, r.Limit)
+	assert.Equal(t, 4990, r.Remaining)
+	assert.Equal(t, 10, r.Used)
+	assert.Equal(t, "core", r.Resource)
+	assert.Equal(t, time.Unix(1700000000, 0), r.Reset)
+	assert.Equal(t, r, notified, "OnRateLimit should be called")
+}
+
+func TestClient_SecondaryRateLimitRetry(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+			return
+		}
+		if requests == 2 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+			return
+		}
+		w.Write([]byte("[]"))
+	})
+	var waits []time.Duration
+	c.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	_, err := c.ListGists(ListOptions{})
+	require.Nil(t, err)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, []time.Duration{30 * time.Second, 2 * time.Minute}, waits, "should wait for Retry-After, then back off")
+}
+
+func TestClient_PrimaryRateLimitNotRetried(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+	})
+	c.sleep = func(time.Duration) { t.Fatal("should not wait for the primary rate limit") }
+
+	_, err := c.ListGists(ListOptions{})
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 0, c.RateLimit().Remaining)
+}
+
+func TestClient_RetriesExhausted(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c.MaxRetries = 2
+	waits := 0
+	c.sleep = func(time.Duration) { waits++ }
+	_, err := c.ListGists(ListOptions{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, waits)
+}
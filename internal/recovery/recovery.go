@@ -0,0 +1,142 @@
+// Package recovery keeps a journal of documents with unsaved changes, so that
+// they can be restored if the app crashes before they are saved
+package recovery
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// Document kinds
+const (
+	KindFile = "file" // a local file
+	KindGist = "gist" // a gist, which may not yet have been created on Github
+)
+
+// Entry is the journal of a document with unsaved changes
+type Entry struct {
+	ID          string    `json:"id"` // identifies the open document; see NewID
+	Kind        string    `json:"kind"`
+	Title       string    `json:"title"`
+	LocalURI    string    `json:"local_uri,omitempty"`   // location of a local file
+	GistID      string    `json:"gist_id,omitempty"`     // empty for gists not yet created
+	Account     string    `json:"account,omitempty"`     // the account the gist belongs to
+	Description string    `json:"description,omitempty"` // gist description
+	Public      bool      `json:"public,omitempty"`      // gist visibility
+	Files       []File    `json:"files"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// File is the unsaved content of a file in a document
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Valid document IDs, which are used as filenames
+var idPattern = regexp.MustCompile("^[0-9a-f]{16}$")
+
+// NewID returns a new random document ID
+func NewID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("read random bytes: %s", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Journal stores entries as JSON files in a directory, one file per document
+type Journal struct {
+	Dir string
+}
+
+// New returns a Journal stored in dir
+func (j Journal) New(dir string) *Journal {
+	return &Journal{Dir: dir}
+}
+
+// Write saves an entry, replacing any earlier entry for the same document.
+// Entries may hold private drafts, so they are only readable by the user.
+func (j *Journal) Write(e Entry) error {
+	fp, err := j.path(e.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(j.Dir, 0700); err != nil {
+		return fmt.Errorf("create recovery dir: %w", err)
+	}
+	tmp := fp + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write recovery journal: %w", err)
+	}
+	if err := os.Rename(tmp, fp); err != nil {
+		return fmt.Errorf("write recovery journal: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the entry for a document. Removing an entry that doesn't exist is not an error.
+func (j *Journal) Remove(id string) error {
+	fp, err := j.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove recovery journal: %w", err)
+	}
+	return nil
+}
+
+// List returns the saved entries, most recently updated first.
+// Unreadable entries are skipped.
+func (j *Journal) List() ([]Entry, error) {
+	files, err := os.ReadDir(j.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read recovery dir: %w", err)
+	}
+	var res []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.Dir, f.Name()))
+		if err != nil {
+			logger.Error("read recovery journal failed", err)
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil || !idPattern.MatchString(e.ID) {
+			logger.Warn("skipping invalid recovery journal %s", f.Name())
+			continue
+		}
+		res = append(res, e)
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].UpdatedAt.After(res[b].UpdatedAt) })
+	return res, nil
+}
+
+// path returns the journal file of a document
+func (j *Journal) path(id string) (string, error) {
+	if !idPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid document ID: %q", id)
+	}
+	return filepath.Join(j.Dir, id+".json"), nil
+}
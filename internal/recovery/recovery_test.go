@@ -0,0 +1,46 @@
+package recovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	j := Journal{}.New(filepath.Join(t.TempDir(), "recovery"))
+	entries, err := j.List()
+	require.Nil(t, err)
+	assert.Empty(t, entries, "a missing journal dir has no entries")
+
+	older := Entry{ID: NewID(), Kind: KindFile, Title: "notes.md", Files: []File{{"notes.md", "# Notes"}}, UpdatedAt: time.Unix(100, 0)}
+	newer := Entry{ID: NewID(), Kind: KindGist, Title: "New Gist", Files: []File{{"a.md", "a"}}, UpdatedAt: time.Unix(200, 0)}
+	require.Nil(t, j.Write(older))
+	require.Nil(t, j.Write(newer))
+	older.Files[0].Content = "# Edited"
+	require.Nil(t, j.Write(older))
+
+	entries, err = j.List()
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, newer.ID, entries[0].ID, "the most recent entry should be first")
+	assert.Equal(t, "# Edited", entries[1].Files[0].Content, "writes should replace the document's entry")
+
+	info, err := os.Stat(filepath.Join(j.Dir, older.ID+".json"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.Nil(t, j.Remove(older.ID))
+	require.Nil(t, j.Remove(older.ID), "removing a missing entry is not an error")
+	entries, _ = j.List()
+	assert.Len(t, entries, 1)
+
+	assert.NotNil(t, j.Write(Entry{ID: "../config"}), "IDs should not be paths")
+	require.Nil(t, os.WriteFile(filepath.Join(j.Dir, "bad.json"), []byte("{"), 0600))
+	entries, err = j.List()
+	require.Nil(t, err)
+	assert.Len(t, entries, 1, "invalid entries should be skipped")
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package secrets
+
+import (
+	"path/filepath"
+
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// defaultStore returns the Secret Service keyring, or the encrypted file store
+// if no keyring is running
+func defaultStore(dir string) Store {
+	k, err := NewKeyringStore("gist-editor")
+	if err == nil {
+		return k
+	}
+	logger.Warn("keyring not available, using encrypted file: %s", err.Error())
+	return NewFileStore(filepath.Join(dir, "secrets.enc"))
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package secrets
+
+import "path/filepath"
+
+// defaultStore returns the encrypted file store
+func defaultStore(dir string) Store {
+	return NewFileStore(filepath.Join(dir, "secrets.enc"))
+}
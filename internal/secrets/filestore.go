@@ -0,0 +1,170 @@
+// Encrypted file store, used where no system keyring is available
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Files identifying the machine, used to derive the file store's encryption key
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// FileStore is a Store that keeps secrets in an AES-GCM encrypted file.
+// The key is derived from the machine ID, the user, and a random salt stored in
+// the file. This keeps secrets out of plain text, and unreadable if the file is
+// copied elsewhere, but can't protect them from other programs run by the same user.
+type FileStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// fileContents is the JSON format of the encrypted file
+type fileContents struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"` // the encrypted JSON map of secrets
+}
+
+// NewFileStore returns a FileStore using the given file
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (f *FileStore) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, _, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	v, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (f *FileStore) Set(key string, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, salt, err := f.read()
+	if err != nil {
+		return err
+	}
+	secrets[key] = value
+	return f.write(secrets, salt)
+}
+
+func (f *FileStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, salt, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return nil
+	}
+	delete(secrets, key)
+	return f.write(secrets, salt)
+}
+
+func (f *FileStore) Name() string { return "encrypted file " + f.Path }
+
+// read decrypts the file, and returns the secrets and salt.
+// Returns an empty map and a new salt if the file does not exist.
+func (f *FileStore) read() (map[string]string, []byte, error) {
+	secrets := map[string]string{}
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, nil, fmt.Errorf("generate salt: %w", err)
+		}
+		return secrets, salt, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read secrets file: %w", err)
+	}
+	var c fileContents
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, nil, fmt.Errorf("invalid secrets file %s: %w", f.Path, err)
+	}
+	gcm, err := newCipher(c.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, err := gcm.Open(nil, c.Nonce, c.Data, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decrypt secrets file %s: %w", f.Path, err)
+	}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, nil, fmt.Errorf("invalid secrets file %s: %w", f.Path, err)
+	}
+	return secrets, c.Salt, nil
+}
+
+// write encrypts and saves the secrets, readable by the user only
+func (f *FileStore) write(secrets map[string]string, salt []byte) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := newCipher(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	data, err := json.Marshal(fileContents{Salt: salt, Nonce: nonce, Data: gcm.Seal(nil, nonce, plain, nil)})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return fmt.Errorf("create secrets dir: %w", err)
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write secrets file: %w", err)
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// newCipher returns the AES-GCM cipher for the given salt
+func newCipher(salt []byte) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write([]byte(machineID()))
+	if u, err := user.Current(); err == nil {
+		h.Write([]byte(u.Uid + u.Username))
+	}
+	h.Write(salt)
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// machineID returns an identifier of the machine, or the hostname if there is none
+func machineID() string {
+	for _, fp := range machineIDFiles {
+		if data, err := os.ReadFile(fp); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	host, _ := os.Hostname()
+	return host
+}
@@ -0,0 +1,186 @@
+//go:build linux
+
+// Secret Service (GNOME Keyring, KWallet) store over D-Bus
+package secrets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	secretServiceName = "org.freedesktop.secrets"
+	secretServicePath = "/org/freedesktop/secrets"
+	defaultCollection = "/org/freedesktop/secrets/aliases/default"
+	secretInterface   = "org.freedesktop.Secret"
+)
+
+// Time to wait for the user to unlock the keyring
+var promptTimeout = 2 * time.Minute
+
+// KeyringStore is a Store using the freedesktop Secret Service, such as GNOME Keyring.
+// Secrets are stored as items with the attributes service=<Service> and key=<key>.
+type KeyringStore struct {
+	Service string
+	conn    *dbus.Conn
+}
+
+// secret is the D-Bus Secret struct
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// NewKeyringStore connects to the Secret Service on the session bus.
+// Returns an error if no Secret Service is running.
+func NewKeyringStore(service string) (*KeyringStore, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect to session bus: %w", err)
+	}
+	var owned bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, secretServiceName).Store(&owned); err != nil || !owned {
+		return nil, fmt.Errorf("no Secret Service running")
+	}
+	return &KeyringStore{Service: service, conn: conn}, nil
+}
+
+func (k *KeyringStore) Get(key string) (string, error) {
+	item, err := k.find(key)
+	if err != nil {
+		return "", fmt.Errorf("find secret: %w", err)
+	}
+	if item == "" {
+		return "", ErrNotFound
+	}
+	session, err := k.openSession()
+	if err != nil {
+		return "", err
+	}
+	defer k.closeSession(session)
+	var s secret
+	if err := k.conn.Object(secretServiceName, item).Call(secretInterface+".Item.GetSecret", 0, session).Store(&s); err != nil {
+		return "", fmt.Errorf("get secret: %w", err)
+	}
+	return string(s.Value), nil
+}
+
+func (k *KeyringStore) Set(key string, value string) error {
+	session, err := k.openSession()
+	if err != nil {
+		return err
+	}
+	defer k.closeSession(session)
+	props := map[string]dbus.Variant{
+		secretInterface + ".Item.Label":      dbus.MakeVariant("Gist Editor: " + key),
+		secretInterface + ".Item.Attributes": dbus.MakeVariant(k.attributes(key)),
+	}
+	s := secret{Session: session, Value: []byte(value), ContentType: "text/plain"}
+	var item, prompt dbus.ObjectPath
+	coll := k.conn.Object(secretServiceName, defaultCollection)
+	if err := coll.Call(secretInterface+".Collection.CreateItem", 0, props, s, true).Store(&item, &prompt); err != nil {
+		return fmt.Errorf("store secret: %w", err)
+	}
+	return k.prompt(prompt)
+}
+
+func (k *KeyringStore) Delete(key string) error {
+	item, err := k.find(key)
+	if err != nil || item == "" {
+		return err
+	}
+	var prompt dbus.ObjectPath
+	if err := k.conn.Object(secretServiceName, item).Call(secretInterface+".Item.Delete", 0).Store(&prompt); err != nil {
+		return fmt.Errorf("delete secret: %w", err)
+	}
+	return k.prompt(prompt)
+}
+
+func (k *KeyringStore) Name() string { return "Secret Service keyring" }
+
+// attributes returns the lookup attributes of a key
+func (k *KeyringStore) attributes(key string) map[string]string {
+	return map[string]string{"service": k.Service, "key": key}
+}
+
+// find returns the item holding the key, unlocking it if needed, or "" if not found
+func (k *KeyringStore) find(key string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	svc := k.conn.Object(secretServiceName, secretServicePath)
+	if err := svc.Call(secretInterface+".Service.SearchItems", 0, k.attributes(key)).Store(&unlocked, &locked); err != nil {
+		return "", fmt.Errorf("search secrets: %w", err)
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", nil
+	}
+	var prompt dbus.ObjectPath
+	if err := svc.Call(secretInterface+".Service.Unlock", 0, locked[:1]).Store(&unlocked, &prompt); err != nil {
+		return "", fmt.Errorf("unlock keyring: %w", err)
+	}
+	if err := k.prompt(prompt); err != nil {
+		return "", err
+	}
+	return locked[0], nil
+}
+
+// openSession opens an unencrypted session; secrets are only sent over the local session bus
+func (k *KeyringStore) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	svc := k.conn.Object(secretServiceName, secretServicePath)
+	if err := svc.Call(secretInterface+".Service.OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return "", fmt.Errorf("open keyring session: %w", err)
+	}
+	return session, nil
+}
+
+// closeSession closes a session opened by openSession
+func (k *KeyringStore) closeSession(session dbus.ObjectPath) {
+	k.conn.Object(secretServiceName, session).Call(secretInterface+".Session.Close", 0)
+}
+
+// prompt shows a keyring prompt, such as an unlock dialog, and waits for it to complete.
+// The path "/" means no prompt is needed.
+func (k *KeyringStore) prompt(path dbus.ObjectPath) error {
+	if path == "/" || path == "" {
+		return nil
+	}
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(secretInterface + ".Prompt"),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := k.conn.AddMatchSignal(match...); err != nil {
+		return fmt.Errorf("watch keyring prompt: %w", err)
+	}
+	defer k.conn.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 1)
+	k.conn.Signal(signals)
+	defer k.conn.RemoveSignal(signals)
+
+	if err := k.conn.Object(secretServiceName, path).Call(secretInterface+".Prompt.Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("show keyring prompt: %w", err)
+	}
+	timeout := time.After(promptTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != path || len(sig.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return fmt.Errorf("keyring prompt was dismissed")
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("keyring prompt timed out")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package secrets stores credentials, such as the Github token, outside of the
+// plain text config files
+package secrets
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned when a secret does not exist in the store
+var ErrNotFound = errors.New("secret not found")
+
+// Store is a credential store, holding secrets by key
+type Store interface {
+	Get(key string) (string, error) // returns ErrNotFound if the key does not exist
+	Set(key string, value string) error
+	Delete(key string) error // deleting a key that does not exist is not an error
+	Name() string            // a description of the store, for logging
+}
+
+// Default returns the platform's default store. dir is the app config directory,
+// used by the encrypted file store where no system keyring is available.
+func Default(dir string) Store {
+	return defaultStore(dir)
+}
+
+// MemoryStore is a Store that keeps secrets in memory only, for testing
+type MemoryStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewMemoryStore returns a new, empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{secrets: map[string]string{}}
+}
+
+func (m *MemoryStore) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (m *MemoryStore) Set(key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[key] = value
+	return nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.secrets, key)
+	return nil
+}
+
+func (m *MemoryStore) Name() string { return "memory" }
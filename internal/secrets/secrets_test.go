@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore checks the behaviour common to all stores
+func testStore(t *testing.T, s Store) {
+	_, err := s.Get("token")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.Nil(t, s.Set("token", "ghp_abc123"))
+	require.Nil(t, s.Set("other", "xyz"))
+	v, err := s.Get("token")
+	require.Nil(t, err)
+	assert.Equal(t, "ghp_abc123", v)
+
+	require.Nil(t, s.Set("token", "ghp_updated"))
+	v, _ = s.Get("token")
+	assert.Equal(t, "ghp_updated", v)
+
+	require.Nil(t, s.Delete("token"))
+	require.Nil(t, s.Delete("token"), "deleting a missing key should succeed")
+	_, err = s.Get("token")
+	assert.ErrorIs(t, err, ErrNotFound)
+	v, _ = s.Get("other")
+	assert.Equal(t, "xyz", v)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "secrets.enc")
+	testStore(t, NewFileStore(fp))
+
+	// The file is private, and the secrets are not stored in plain text
+	info, err := os.Stat(fp)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(fp)
+	require.Nil(t, err)
+	assert.NotContains(t, string(data), "xyz")
+
+	// A new store reads the existing file
+	v, err := NewFileStore(fp).Get("other")
+	require.Nil(t, err)
+	assert.Equal(t, "xyz", v)
+}
@@ -0,0 +1,149 @@
+// Package session stores the recently used documents, and the documents open
+// when the app was closed, so they can be reopened on the next launch
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the name of the session file in the config directory
+const FileName = "session.json"
+
+// MaxRecent is the number of recently used documents kept
+const MaxRecent = 10
+
+// Document kinds
+const (
+	KindFile = "file" // a local file
+	KindGist = "gist" // a gist on Github
+)
+
+// Document is a local file or gist
+type Document struct {
+	Kind     string `json:"kind"`
+	Title    string `json:"title"`
+	LocalURI string `json:"local_uri,omitempty"` // location of a local file
+	GistID   string `json:"gist_id,omitempty"`
+	Account  string `json:"account,omitempty"` // the account the gist belongs to
+}
+
+// Same returns true if both are the same file or gist
+func (d Document) Same(other Document) bool {
+	if d.Kind != other.Kind {
+		return false
+	}
+	if d.Kind == KindFile {
+		return d.LocalURI == other.LocalURI
+	}
+	return d.GistID == other.GistID && d.Account == other.Account
+}
+
+// Valid checks the document identifies a file or gist
+func (d Document) Valid() error {
+	switch d.Kind {
+	case KindFile:
+		if d.LocalURI == "" {
+			return fmt.Errorf("file has no location")
+		}
+	case KindGist:
+		if d.GistID == "" {
+			return fmt.Errorf("gist has no ID")
+		}
+	default:
+		return fmt.Errorf("unknown document kind %q", d.Kind)
+	}
+	return nil
+}
+
+// OpenDocument is a document that was open when the app was closed, with the state of its editor tab
+type OpenDocument struct {
+	Document
+	Active        bool    `json:"active,omitempty"`      // true for the document in the active tab
+	ActiveFile    string  `json:"active_file,omitempty"` // the gist file shown in the editor
+	CursorRow     int     `json:"cursor_row"`
+	CursorColumn  int     `json:"cursor_column"`
+	Preview       bool    `json:"preview"`                  // true if the markdown preview was shown
+	PreviewOffset float64 `json:"preview_offset,omitempty"` // position of the divider between the editor and preview, from 0-1
+}
+
+// State is the saved session
+type State struct {
+	Recent []Document     `json:"recent"` // most recently used first
+	Open   []OpenDocument `json:"open"`
+}
+
+// AddRecent moves the document to the top of the recently used list,
+// dropping the least recently used documents beyond MaxRecent
+func (s *State) AddRecent(d Document) {
+	s.RemoveRecent(d)
+	s.Recent = append([]Document{d}, s.Recent...)
+	if len(s.Recent) > MaxRecent {
+		s.Recent = s.Recent[:MaxRecent]
+	}
+}
+
+// RemoveRecent removes the document from the recently used list
+func (s *State) RemoveRecent(d Document) {
+	var res []Document
+	for _, x := range s.Recent {
+		if !x.Same(d) {
+			res = append(res, x)
+		}
+	}
+	s.Recent = res
+}
+
+// Load reads the session file. A missing file is an empty session.
+// Invalid documents are dropped.
+func Load(fp string) (State, error) {
+	var s State
+	data, err := os.ReadFile(fp)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("read session: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return State{}, fmt.Errorf("invalid session file %s: %w", fp, err)
+	}
+	recent := s.Recent
+	s.Recent = nil
+	for _, d := range recent {
+		if d.Valid() == nil && len(s.Recent) < MaxRecent {
+			s.Recent = append(s.Recent, d)
+		}
+	}
+	var open []OpenDocument
+	for _, d := range s.Open {
+		if d.Valid() == nil {
+			open = append(open, d)
+		}
+	}
+	s.Open = open
+	return s, nil
+}
+
+// Save writes the session file. File locations and gist titles are private,
+// so the file is only readable by the user.
+func Save(fp string, s State) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+		return fmt.Errorf("create session dir: %w", err)
+	}
+	tmp := fp + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	if err := os.Rename(tmp, fp); err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	return nil
+}
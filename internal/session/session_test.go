@@ -0,0 +1,60 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddRecent(t *testing.T) {
+	var s State
+	notes := Document{Kind: KindFile, Title: "notes.md", LocalURI: "file:///tmp/notes.md"}
+	gist := Document{Kind: KindGist, Title: "todo.md", GistID: "abc123", Account: "default"}
+	s.AddRecent(notes)
+	s.AddRecent(gist)
+	s.AddRecent(notes)
+	assert.Equal(t, []Document{notes, gist}, s.Recent, "reopened documents should move to the top")
+
+	other := gist
+	other.Account = "work"
+	s.AddRecent(other)
+	assert.Len(t, s.Recent, 3, "gists of different accounts are different documents")
+
+	for i := 0; i < MaxRecent+5; i++ {
+		s.AddRecent(Document{Kind: KindGist, GistID: fmt.Sprint(i)})
+	}
+	assert.Len(t, s.Recent, MaxRecent)
+	assert.Equal(t, fmt.Sprint(MaxRecent+4), s.Recent[0].GistID)
+
+	s.RemoveRecent(s.Recent[0])
+	assert.Len(t, s.Recent, MaxRecent-1)
+}
+
+func TestLoadSave(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "config", FileName)
+	s, err := Load(fp)
+	require.Nil(t, err)
+	assert.Empty(t, s.Recent, "a missing session file is an empty session")
+
+	notes := Document{Kind: KindFile, Title: "notes.md", LocalURI: "file:///tmp/notes.md"}
+	s.AddRecent(notes)
+	s.AddRecent(Document{Kind: KindGist, Title: "no ID"})
+	s.Open = []OpenDocument{{Document: notes, CursorRow: 3, CursorColumn: 2, Preview: true, PreviewOffset: 0.5}}
+	require.Nil(t, Save(fp, s))
+	info, err := os.Stat(fp)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := Load(fp)
+	require.Nil(t, err)
+	assert.Equal(t, []Document{notes}, loaded.Recent, "invalid documents should be dropped")
+	assert.Equal(t, s.Open, loaded.Open)
+
+	require.Nil(t, os.WriteFile(fp, []byte("{"), 0600))
+	_, err = Load(fp)
+	assert.NotNil(t, err)
+}
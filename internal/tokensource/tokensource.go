@@ -0,0 +1,126 @@
+// Package tokensource finds Github tokens set up by other tools: environment
+// variables, and the gh CLI's hosts file. The precedence follows the gh CLI.
+package tokensource
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Token source names, as used in the config file's token_sources setting
+const (
+	Store = "store" // the app's secret store
+	Env   = "env"   // environment variables
+	GhCLI = "gh"    // the gh CLI's hosts file
+)
+
+// DefaultOrder is the order token sources are searched in: tokens saved in the
+// app take precedence over those set up for other tools
+var DefaultOrder = []string{Store, Env, GhCLI}
+
+// Token is a Github token, and where it was found
+type Token struct {
+	Value  string
+	Source string // one of Store, Env or GhCLI
+	Detail string // eg: the environment variable name, or the file path
+}
+
+// Describe returns a description of where the token was found, for display
+func (t Token) Describe() string {
+	switch t.Source {
+	case Store:
+		return "saved in " + t.Detail
+	case Env:
+		return "environment variable " + t.Detail
+	case GhCLI:
+		return "gh CLI login, " + t.Detail
+	}
+	return "not set"
+}
+
+// Valid returns an error if the name is not a known token source
+func Valid(name string) error {
+	for _, s := range DefaultOrder {
+		if name == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown token source %q, expected one of %v", name, DefaultOrder)
+}
+
+// Environment variables holding tokens, in order of precedence.
+// As with the gh CLI, Github Enterprise Server hosts use their own variables.
+var (
+	envVars           = []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	enterpriseEnvVars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+)
+
+// FromEnv returns the token set in the environment for the host.
+// Returns false if none is set.
+func FromEnv(enterprise bool) (Token, bool) {
+	vars := envVars
+	if enterprise {
+		vars = enterpriseEnvVars
+	}
+	for _, name := range vars {
+		if v := os.Getenv(name); v != "" {
+			return Token{Value: v, Source: Env, Detail: name}, true
+		}
+	}
+	return Token{}, false
+}
+
+// GhHostsFile returns the path of the gh CLI's hosts file: hosts.yml in $GH_CONFIG_DIR,
+// $XDG_CONFIG_HOME/gh, or ~/.config/gh. Returns an empty string if the home directory is unknown.
+func GhHostsFile() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// ghHost is a host entry in the gh CLI's hosts file
+type ghHost struct {
+	User       string `yaml:"user"`
+	OAuthToken string `yaml:"oauth_token"`
+}
+
+// FromGhCLI returns the token the gh CLI stored in its hosts file for the hostname,
+// eg: github.com. Returns false if the file doesn't exist or has no token for the host.
+// Recent gh versions keep the token in the system keyring instead, which isn't read.
+func FromGhCLI(path string, hostname string) (Token, bool, error) {
+	if path == "" {
+		return Token{}, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Token{}, false, nil
+	}
+	if err != nil {
+		return Token{}, false, fmt.Errorf("read gh hosts file: %w", err)
+	}
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return Token{}, false, fmt.Errorf("gh hosts file %s: %w", path, err)
+	}
+	h, ok := hosts[hostname]
+	if !ok || h.OAuthToken == "" {
+		return Token{}, false, nil
+	}
+	detail := path
+	if h.User != "" {
+		detail = h.User + " in " + path
+	}
+	return Token{Value: h.OAuthToken, Source: GhCLI, Detail: detail}, true, nil
+}
@@ -0,0 +1,69 @@
+package tokensource
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "ghp_github")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "")
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "")
+
+	tok, ok := FromEnv(false)
+	require.True(t, ok)
+	assert.Equal(t, Token{Value: "ghp_github", Source: Env, Detail: "GITHUB_TOKEN"}, tok)
+
+	t.Setenv("GH_TOKEN", "ghp_gh")
+	tok, _ = FromEnv(false)
+	assert.Equal(t, "ghp_gh", tok.Value, "GH_TOKEN should take precedence")
+
+	_, ok = FromEnv(true)
+	assert.False(t, ok, "enterprise hosts should only use the enterprise variables")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "ghp_enterprise")
+	tok, _ = FromEnv(true)
+	assert.Equal(t, "ghp_enterprise", tok.Value)
+}
+
+func TestFromGhCLI(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "hosts.yml")
+	data := `github.com:
+    user: octocat
+    oauth_token: gho_abc123
+    git_protocol: https
+github.example.com:
+    user: mona
+`
+	require.Nil(t, os.WriteFile(fp, []byte(data), 0600))
+
+	tok, ok, err := FromGhCLI(fp, "github.com")
+	require.Nil(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "gho_abc123", tok.Value)
+	assert.Equal(t, "gh CLI login, octocat in "+fp, tok.Describe())
+
+	_, ok, err = FromGhCLI(fp, "github.example.com")
+	assert.Nil(t, err)
+	assert.False(t, ok, "hosts without a token in the file should be skipped")
+
+	_, ok, err = FromGhCLI(filepath.Join(t.TempDir(), "missing.yml"), "github.com")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	require.Nil(t, os.WriteFile(fp, []byte("- not a map"), 0600))
+	_, _, err = FromGhCLI(fp, "github.com")
+	assert.NotNil(t, err)
+}
+
+func TestGhHostsFile(t *testing.T) {
+	t.Setenv("GH_CONFIG_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	assert.Equal(t, "/tmp/xdg/gh/hosts.yml", GhHostsFile())
+	t.Setenv("GH_CONFIG_DIR", "/tmp/gh")
+	assert.Equal(t, "/tmp/gh/hosts.yml", GhHostsFile())
+}
@@ -0,0 +1,210 @@
+// Switching between named Github accounts, such as personal and work accounts
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// loadAccount makes the account active in the app: its token is read from the
+// token sources, and the list view and settings window show its data.
+func (cfg *AppConfig) loadAccount(host github.GithubConfig) error {
+	tok, err := ReadGithubToken(host, cfg.Settings.TokenSources)
+	if err != nil {
+		return err
+	}
+	host.GithubAPIToken = tok.Value
+	*cfg.GithubConfig = host
+	cfg.githubUser = nil // the token belongs to a different user
+	if cfg.ListWindow != nil {
+		cfg.ListWindow.Clear()
+		cfg.ListWindow.SetAccount(host.Name)
+	}
+	if cfg.GithubSettingsWindow != nil {
+		cfg.GithubSettingsWindow.setFields(host, tok)
+	}
+	if cfg.MainWindow.RefreshAccounts != nil {
+		cfg.MainWindow.RefreshAccounts()
+	}
+	if tok.Value != "" {
+		logger.Info("github token for account %s: %s", host.Name, tok.Describe())
+	}
+	return nil
+}
+
+// SwitchAccount makes the named account active. Open gists belong to the
+// previous account, so they are closed after the user confirms.
+func (cfg *AppConfig) SwitchAccount(name string) {
+	if name == cfg.GithubConfig.Name {
+		return
+	}
+	cfg.confirmCloseGists("Switch account?", func() {
+		if err := cfg.switchAccount(name); err != nil {
+			logger.Error("switch account failed", err)
+			dialog.ShowError(fmt.Errorf("switching account failed: %w", err), cfg.MainWindow.Window)
+		}
+	})
+}
+
+// switchAccount saves the named account as active, and loads it
+func (cfg *AppConfig) switchAccount(name string) error {
+	settings := *cfg.Settings
+	accounts := settings.GithubAccounts()
+	if err := accounts.SetActive(name); err != nil {
+		return err
+	}
+	settings.SetGithubAccounts(accounts)
+	if err := saveSettings(settings); err != nil {
+		return err
+	}
+	cfg.Settings = &settings
+	logger.Info("switched to account %s", name)
+	return cfg.loadAccount(settings.GithubConfig())
+}
+
+// AddAccount asks for the name and host of a new account, then switches to it
+// and shows the Github settings to sign in
+func (cfg *AppConfig) AddAccount() {
+	w := cfg.MainWindow.Window
+	nameField := widget.NewEntry()
+	nameField.PlaceHolder = "eg: work"
+	hostField := widget.NewEntry()
+	hostField.PlaceHolder = "github.com, or a Github Enterprise Server hostname"
+	items := []*widget.FormItem{
+		widget.NewFormItem("Account name", nameField),
+		widget.NewFormItem("Host", hostField),
+	}
+	dialog.ShowForm("Add Github account", "Add", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		host := accountHost(hostField.Text)
+		host.Name = strings.TrimSpace(nameField.Text)
+		if err := cfg.addAccount(host); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		cfg.confirmCloseGists("Switch account?", func() {
+			if err := cfg.switchAccount(host.Name); err != nil {
+				dialog.ShowError(fmt.Errorf("switching account failed: %w", err), w)
+				return
+			}
+			cfg.ShowGithubTokenModal()
+		})
+	}, w)
+}
+
+// accountHost returns the host settings for a hostname. Empty or github.com use the public Github.
+func accountHost(hostname string) github.GithubConfig {
+	hostname = strings.TrimSpace(hostname)
+	hostname = strings.TrimPrefix(strings.TrimPrefix(hostname, "https://"), "http://")
+	hostname = strings.TrimRight(hostname, "/")
+	if hostname == "" || hostname == "github.com" {
+		return github.GithubConfig{}
+	}
+	return github.EnterpriseConfig(hostname)
+}
+
+// addAccount adds an account to the config file
+func (cfg *AppConfig) addAccount(host github.GithubConfig) error {
+	settings := *cfg.Settings
+	accounts := settings.GithubAccounts()
+	if err := accounts.Add(host); err != nil {
+		return err
+	}
+	settings.SetGithubAccounts(accounts)
+	if err := saveSettings(settings); err != nil {
+		return err
+	}
+	cfg.Settings = &settings
+	logger.Info("added account %s", host.Name)
+	if cfg.MainWindow.RefreshAccounts != nil {
+		cfg.MainWindow.RefreshAccounts()
+	}
+	return nil
+}
+
+// RemoveAccount removes the active account and its token, after the user confirms,
+// and switches to the first remaining account
+func (cfg *AppConfig) RemoveAccount() {
+	w := cfg.MainWindow.Window
+	name := cfg.GithubConfig.Name
+	if len(cfg.Settings.Accounts) < 2 {
+		dialog.ShowInformation("Remove account", "The last account can't be removed.", w)
+		return
+	}
+	msg := fmt.Sprintf("Remove the account %s, and its saved token?", name)
+	dialog.ShowConfirm("Remove account?", msg, func(ok bool) {
+		if !ok {
+			return
+		}
+		cfg.confirmCloseGists("Remove account?", func() {
+			if err := cfg.removeAccount(name); err != nil {
+				logger.Error("remove account failed", err)
+				dialog.ShowError(fmt.Errorf("removing account failed: %w", err), w)
+			}
+		})
+	}, w)
+}
+
+// removeAccount removes the account from the config file and its token from the
+// secret store, then loads the account that becomes active
+func (cfg *AppConfig) removeAccount(name string) error {
+	settings := *cfg.Settings
+	accounts := settings.GithubAccounts()
+	if err := accounts.Remove(name); err != nil {
+		return err
+	}
+	settings.SetGithubAccounts(accounts)
+	if err := saveSettings(settings); err != nil {
+		return err
+	}
+	cfg.Settings = &settings
+	if err := secretStore().Delete(tokenKey(name)); err != nil {
+		logger.Error("remove account token failed", err)
+	}
+	delete(cfg.githubClients, name)
+	logger.Info("removed account %s", name)
+	return cfg.loadAccount(settings.GithubConfig())
+}
+
+// confirmCloseGists calls fn once the open gists, which belong to the active account,
+// have been closed. The user chooses what to do with the unsaved changes of each gist,
+// or, if there are none, confirms closing them.
+func (cfg *AppConfig) confirmCloseGists(title string, fn func()) {
+	isGist := func(f *GistFile) bool { return !f.isLocal }
+	var gists []*Tab
+	dirty := false
+	for _, t := range cfg.EditWindow.Tabs() {
+		if t.File.isOpen && isGist(t.File) {
+			gists = append(gists, t)
+			dirty = dirty || t.File.isDirty
+		}
+	}
+	if len(gists) == 0 {
+		fn()
+		return
+	}
+	closeGists := func() {
+		for t := cfg.EditWindow.find(isGist); t != nil; t = cfg.EditWindow.find(isGist) {
+			cfg.EditWindow.Select(t)
+			cfg.closeFile()
+		}
+		fn()
+	}
+	if dirty {
+		cfg.confirmDiscardTabs(gists, closeGists)
+		return
+	}
+	msg := "The open gists belong to the current account, and will be closed."
+	dialog.ShowConfirm(title, msg, func(ok bool) {
+		if ok {
+			closeGists()
+		}
+	}, cfg.MainWindow.Window)
+}
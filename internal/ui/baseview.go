@@ -4,15 +4,16 @@ package ui
 import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
 // MainWindow is the main app window with menu & methods for create & show
 type MainWindow struct {
-	Window     fyne.Window
-	menu       *fyne.MainMenu
-	SetCanSave func(bool) // toggle whether Save / SaveAs is allowed in the main menu
+	Window          fyne.Window
+	menu            *fyne.MainMenu
+	SetCanSave      func(bool) // toggle whether Save / SaveAs is allowed in the main menu
+	RefreshAccounts func()     // update the account switcher in the main menu
+	RefreshRecent   func()     // update the recent documents in the main menu and welcome screen
 }
 
 // Show shows the main window and starts the application
@@ -33,25 +34,32 @@ func (m MainWindow) New(cfg *AppConfig) MainWindow {
 	w := a.NewWindow("GistEdit")
 	w.Resize(fyne.NewSize(600, 400))
 	w.SetMaster() // master window, when closed closes all other windows
+	w.SetCloseIntercept(cfg.Exit)
 	w.CenterOnScreen()
 
 	// Generate window content UI
-	content := mainWindowUI(cfg)
+	content, refreshWelcome := mainWindowUI(cfg)
 	w.SetContent(content)
 
 	// Create the main menu
-	menu, setCanSave := FileMenu(cfg)
+	menu, setCanSave, refreshAccounts, refreshMenu := FileMenu(cfg)
 	w.SetMainMenu(menu)
 
 	return MainWindow{
-		Window:     w,
-		menu:       menu,
-		SetCanSave: setCanSave,
+		Window:          w,
+		menu:            menu,
+		SetCanSave:      setCanSave,
+		RefreshAccounts: refreshAccounts,
+		RefreshRecent: func() {
+			refreshMenu()
+			refreshWelcome()
+		},
 	}
 }
 
-// mainWindowUI creates the main window UI content
-func mainWindowUI(cfg *AppConfig) *fyne.Container {
+// mainWindowUI creates the main window UI content, and returns a function to update
+// its list of recent documents
+func mainWindowUI(cfg *AppConfig) (*fyne.Container, func()) {
 
 	// Title
 	title := TitleText("Welcome to the Gist editor!")
@@ -68,9 +76,26 @@ func mainWindowUI(cfg *AppConfig) *fyne.Container {
 	// Centered buttons grid
 	buttons := container.NewGridWithColumns(3, newGistButton, viewGistsButton, closeBtn)
 
-	spacer := layout.NewSpacer()
+	// Recently used documents, between the welcome text and the buttons
+	recentList := container.NewVBox()
+	recentPane := container.NewBorder(widget.NewLabel("Recent"), nil, nil, nil, container.NewVScroll(recentList))
+	recentPane.Hide()
+	refreshRecent := func() {
+		recentList.RemoveAll()
+		for _, d := range cfg.sessionState().Recent {
+			d := d
+			b := widget.NewButton(recentLabel(d), func() { cfg.OpenRecent(d) })
+			b.Alignment = widget.ButtonAlignLeading
+			b.Importance = widget.LowImportance
+			recentList.Add(b)
+		}
+		if len(recentList.Objects) == 0 {
+			recentPane.Hide()
+		} else {
+			recentPane.Show()
+		}
+	}
 
-	// Vertical grid layout
-	content := container.New(layout.NewGridLayoutWithRows(5), titleContainer, spacer, spacer, spacer, buttons)
-	return content
+	content := container.NewBorder(titleContainer, buttons, nil, nil, recentPane)
+	return content, refreshRecent
 }
@@ -0,0 +1,155 @@
+// Browse view: other users' public gists and the public timeline, read-only with a preview
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// BrowseView lists the public gists of any user, or the public timeline,
+// with a preview of the selected gist
+type BrowseView struct {
+	Content    *container.Split
+	section    *gistSection
+	userField  *widget.Entry
+	preview    *widget.RichText
+	openButton *widget.Button
+	selected   string // ID of the previewed gist
+	username   string // the user whose gists are listed, or empty for the public timeline
+	chosen     bool   // true once a user or the public timeline has been chosen
+	mu         sync.Mutex
+	cfg        *AppConfig
+}
+
+// newBrowseView returns a new BrowseView. Nothing is listed until a user or the public timeline is chosen.
+func newBrowseView(cfg *AppConfig, w fyne.Window) *BrowseView {
+	bv := &BrowseView{cfg: cfg}
+	bv.section = newGistSection(bv.fetchPage, nil, bv.showPreview(cfg, w), true, func(err error) {
+		dialog.ShowError(fmt.Errorf("loading gists failed: %w", err), w)
+	})
+
+	bv.userField = widget.NewEntry()
+	bv.userField.PlaceHolder = "Github username"
+	bv.userField.OnSubmitted = func(string) { bv.showUser(w) }
+	userButton := widget.NewButton("Show", func() { bv.showUser(w) })
+	publicButton := widget.NewButton("Public timeline", func() {
+		bv.setUsername("")
+		bv.Reset()
+		bv.section.Reload()
+	})
+	search := container.NewBorder(nil, nil, nil, container.NewHBox(userButton, publicButton), bv.userField)
+	listPane := container.NewBorder(search, nil, nil, nil, bv.section.list)
+
+	bv.preview = widget.NewRichText()
+	bv.preview.Wrapping = fyne.TextWrapWord
+	bv.openButton = widget.NewButton("Open read-only", func() {
+		if bv.selected != "" {
+			cfg.OpenGist(bv.selected)
+		}
+	})
+	bv.openButton.Disable()
+	previewPane := container.NewBorder(widget.NewLabel("Preview"), bv.openButton, nil, nil, container.NewVScroll(bv.preview))
+
+	bv.Content = container.NewHSplit(listPane, previewPane)
+	bv.Content.SetOffset(0.4)
+	return bv
+}
+
+// Refresh reloads the listed gists
+func (b *BrowseView) Refresh() {
+	b.section.Refresh()
+}
+
+// Reset clears the listed gists and the preview
+func (b *BrowseView) Reset() {
+	b.section.Reset()
+	b.selected = ""
+	b.preview.ParseMarkdown("")
+	b.openButton.Disable()
+}
+
+// showUser lists the gists of the user in the username field
+func (b *BrowseView) showUser(w fyne.Window) {
+	name := strings.TrimSpace(b.userField.Text)
+	if err := github.ValidUsername(name); err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	b.setUsername(name)
+	b.Reset()
+	b.section.Reload()
+}
+
+// setUsername sets the user whose gists are listed, or empty for the public timeline
+func (b *BrowseView) setUsername(name string) {
+	b.mu.Lock()
+	b.username = name
+	b.chosen = true
+	b.mu.Unlock()
+}
+
+// fetchPage fetches a page of the chosen user's gists, or the public timeline.
+// Returns an empty page if neither has been chosen.
+func (b *BrowseView) fetchPage(opts github.ListOptions) (github.GistPage, error) {
+	b.mu.Lock()
+	name, chosen := b.username, b.chosen
+	b.mu.Unlock()
+	if !chosen {
+		return github.GistPage{}, nil
+	}
+	if name == "" {
+		return b.cfg.GithubClient().ListPublicGists(opts)
+	}
+	return b.cfg.GithubClient().ListUserGists(name, opts)
+}
+
+// showPreview returns the list selection handler, which loads the selected gist into the preview pane
+func (b *BrowseView) showPreview(cfg *AppConfig, w fyne.Window) func(github.Gist) {
+	return func(g github.Gist) {
+		b.selected = g.ID
+		b.openButton.Disable()
+		b.preview.ParseMarkdown("Loading...")
+		go func() {
+			full, err := cfg.GithubClient().GetGist(g.ID)
+			if g.ID != b.selected {
+				return // a different gist was selected while loading
+			}
+			if err != nil {
+				logger.Error("load gist preview failed", err)
+				b.preview.ParseMarkdown("")
+				dialog.ShowError(fmt.Errorf("loading gist failed: %w", err), w)
+				return
+			}
+			b.preview.ParseMarkdown(previewMarkdown(full))
+			b.openButton.Enable()
+		}()
+	}
+}
+
+// previewMarkdown returns the markdown for previewing a gist: its description,
+// then each file. Markdown files are rendered; other files are shown as code.
+func previewMarkdown(g github.Gist) string {
+	var sb strings.Builder
+	if g.Description != "" {
+		sb.WriteString("*" + g.Description + "*\n\n")
+	}
+	for _, f := range g.Files {
+		sb.WriteString("## " + f.Filename + "\n\n")
+		ext := strings.ToLower(path.Ext(f.Filename))
+		if ext == ".md" || ext == ".markdown" {
+			sb.WriteString(f.Content + "\n\n")
+			continue
+		}
+		sb.WriteString("```\n" + f.Content + "\n```\n\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,231 @@
+// Comments pane for the gist editor
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// CommentsPane lists the comments on the open gist, rendered as markdown,
+// and allows posting, editing and deleting the user's own comments.
+type CommentsPane struct {
+	Content      *fyne.Container
+	ToggleButton *widget.Button   // the toggle Comments button
+	split        *container.Split // split view of the editor and comments pane
+	cfg          *AppConfig
+	list         *fyne.Container // the rendered comments
+	input        *widget.Entry   // new comment entry
+	postButton   *widget.Button
+	status       *widget.Label
+	comments     []github.Comment
+	gistID       string
+	login        string // login name of the current user, to find their own comments
+	window       fyne.Window
+}
+
+// New returns a new, empty CommentsPane
+func (c CommentsPane) New(cfg *AppConfig, w fyne.Window) *CommentsPane {
+	cp := &CommentsPane{
+		list:   container.NewVBox(),
+		status: widget.NewLabel(""),
+		window: w,
+	}
+	cp.input = widget.NewMultiLineEntry()
+	cp.input.PlaceHolder = "Leave a comment (markdown)..."
+	cp.input.SetMinRowsVisible(3)
+	cp.postButton = widget.NewButton("Comment", func() { cp.post(cfg) })
+
+	refreshButton := widget.NewButton("Refresh", func() { cp.Load(cfg, cp.gistID) })
+	top := container.NewBorder(nil, nil, TitleText("Comments"), refreshButton)
+	bottom := container.NewVBox(cp.input, container.NewBorder(nil, nil, nil, cp.postButton, cp.status))
+	cp.Content = container.NewBorder(top, bottom, nil, nil, container.NewVScroll(cp.list))
+	return cp
+}
+
+// attach places the pane in the right side of a split view, collapsed
+func (c *CommentsPane) attach(cfg *AppConfig, split *container.Split) {
+	c.cfg = cfg
+	c.split = split
+	c.Content.Hide()
+	split.SetOffset(1.0)
+	c.ToggleButton = widget.NewButton("Show comments", c.Toggle)
+}
+
+// IsVisible returns whether the comments pane is visible
+func (c *CommentsPane) IsVisible() bool {
+	return c.Content.Visible() && c.split.Offset < 0.9
+}
+
+// Toggle shows or hides the comments pane, loading the comments when shown
+func (c *CommentsPane) Toggle() {
+	if c.IsVisible() {
+		c.Content.Hide()
+		c.split.SetOffset(1.0)
+		c.ToggleButton.SetText("Show comments")
+		return
+	}
+	c.Content.Show()
+	c.split.SetOffset(0.65)
+	c.ToggleButton.SetText("Hide comments")
+	c.Load(c.cfg, c.gistID)
+}
+
+// SetGist sets the gist whose comments are shown.
+// Comments are only loaded while the pane is visible.
+func (c *CommentsPane) SetGist(gistID string) {
+	c.gistID = gistID
+	if c.split != nil && c.IsVisible() {
+		c.Load(c.cfg, gistID)
+		return
+	}
+	c.comments = nil
+	c.list.RemoveAll()
+}
+
+// Load fetches and shows the comments of a gist.
+// An empty gist ID clears the pane, for gists not yet saved to Github.
+func (c *CommentsPane) Load(cfg *AppConfig, gistID string) {
+	c.gistID = gistID
+	c.comments = nil
+	c.render(cfg)
+	if gistID == "" {
+		c.status.SetText("Save the gist to Github to add comments.")
+		c.postButton.Disable()
+		return
+	}
+	c.postButton.Enable()
+	c.status.SetText("Loading comments...")
+	go func() {
+		c.login = cfg.githubLogin()
+		comments, err := cfg.GithubClient().ListComments(gistID)
+		if gistID != c.gistID {
+			return // a different gist was opened while loading
+		}
+		if err != nil {
+			logger.Error("list comments failed", err)
+			c.status.SetText("Loading comments failed.")
+			return
+		}
+		c.comments = comments
+		c.status.SetText(fmt.Sprintf("%d comments", len(comments)))
+		c.render(cfg)
+	}()
+}
+
+// render rebuilds the rendered comments list
+func (c *CommentsPane) render(cfg *AppConfig) {
+	c.list.RemoveAll()
+	for _, x := range c.comments {
+		c.list.Add(c.commentItem(cfg, x, x.Author != "" && x.Author == c.login))
+	}
+	c.list.Refresh()
+}
+
+// commentItem returns the widget for a single comment.
+// Edit and delete buttons are shown for the user's own comments.
+func (c *CommentsPane) commentItem(cfg *AppConfig, x github.Comment, own bool) fyne.CanvasObject {
+	header := widget.NewLabel(fmt.Sprintf("%s - %s", x.Author, x.CreatedAt.Local().Format("2006-01-02 15:04")))
+	header.TextStyle.Bold = true
+	body := widget.NewRichTextFromMarkdown(x.Body)
+	body.Wrapping = fyne.TextWrapWord
+	var actions fyne.CanvasObject
+	if own {
+		editButton := widget.NewButton("Edit", func() { c.showEditDialog(cfg, x) })
+		deleteButton := widget.NewButton("Delete", func() { c.confirmDelete(cfg, x) })
+		actions = container.NewHBox(editButton, deleteButton)
+	}
+	return container.NewVBox(container.NewBorder(nil, nil, header, actions), body, widget.NewSeparator())
+}
+
+// post creates a new comment from the input text, in the background
+func (c *CommentsPane) post(cfg *AppConfig) {
+	if c.gistID == "" || c.input.Text == "" {
+		return
+	}
+	gistID, body := c.gistID, c.input.Text
+	c.postButton.Disable()
+	go func() {
+		created, err := cfg.GithubClient().CreateComment(gistID, body)
+		c.postButton.Enable()
+		if err != nil {
+			logger.Error("post comment failed", err)
+			dialog.ShowError(fmt.Errorf("posting comment failed: %w", err), c.window)
+			return
+		}
+		if gistID != c.gistID {
+			return // a different gist was opened while posting
+		}
+		if c.input.Text == body {
+			c.input.SetText("")
+		}
+		c.comments = append(c.comments, created)
+		c.render(cfg)
+	}()
+}
+
+// showEditDialog shows a dialog to edit one of the user's comments, saved in the background
+func (c *CommentsPane) showEditDialog(cfg *AppConfig, x github.Comment) {
+	input := widget.NewMultiLineEntry()
+	input.SetText(x.Body)
+	input.SetMinRowsVisible(6)
+	items := []*widget.FormItem{widget.NewFormItem("Comment", input)}
+	d := dialog.NewForm("Edit comment", "Save", "Cancel", items, func(ok bool) {
+		if !ok || input.Text == x.Body {
+			return
+		}
+		gistID, body := c.gistID, input.Text
+		go func() {
+			updated, err := cfg.GithubClient().EditComment(gistID, x.ID, body)
+			if err != nil {
+				logger.Error("edit comment failed", err)
+				dialog.ShowError(fmt.Errorf("editing comment failed: %w", err), c.window)
+				return
+			}
+			if gistID != c.gistID {
+				return // a different gist was opened while saving
+			}
+			for i := range c.comments {
+				if c.comments[i].ID == x.ID {
+					c.comments[i] = updated
+				}
+			}
+			c.render(cfg)
+		}()
+	}, c.window)
+	d.Resize(fyne.NewSize(500, 300))
+	d.Show()
+}
+
+// confirmDelete asks for confirmation, and deletes one of the user's comments in the background
+func (c *CommentsPane) confirmDelete(cfg *AppConfig, x github.Comment) {
+	dialog.ShowConfirm("Delete comment", "Delete this comment?", func(ok bool) {
+		if !ok {
+			return
+		}
+		gistID := c.gistID
+		go func() {
+			if err := cfg.GithubClient().DeleteComment(gistID, x.ID); err != nil {
+				logger.Error("delete comment failed", err)
+				dialog.ShowError(fmt.Errorf("deleting comment failed: %w", err), c.window)
+				return
+			}
+			if gistID != c.gistID {
+				return // a different gist was opened while deleting
+			}
+			var remaining []github.Comment
+			for _, y := range c.comments {
+				if y.ID != x.ID {
+					remaining = append(remaining, y)
+				}
+			}
+			c.comments = remaining
+			c.render(cfg)
+		}()
+	}, c.window)
+}
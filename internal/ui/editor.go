@@ -2,35 +2,79 @@
 package ui
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 	"github.com/fieldse/gist-editor/internal/editor"
 	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/icons"
+	"github.com/fieldse/gist-editor/internal/logger"
 )
 
-// Editor represents the Gist editor window, and provides methods
+// Editor is the editor of a document in a tab of the edit window, and provides methods
 // to update the title & content of the editor widget
 type Editor struct {
 	*editor.MultiLineWidget
 	Title                string
 	editor               *editor.MultiLineWidget // the text editor field
-	editWindow           fyne.Window             // the editor window
+	editWindow           fyne.Window             // the edit window, shared by the editors of all tabs
+	content              *fyne.Container         // the editor's content, shown in its tab
+	tab                  *container.TabItem      // the editor's tab in the edit window
 	previewEditContainer *PreviewEditContainer   // a wrapper, containing the preview and edit widgets
-	IsVisible            bool
+	fileBar              *FileBar                // the gist file selector
+	metadata             *MetadataBar            // the gist description and visibility
+	comments             *CommentsPane           // the gist comments pane
+	actions              *GistActionsBar         // star, fork and history actions
+	progress             *widget.ProgressBar     // download progress of truncated files
+	saveButton           *widget.Button
+	forkButton           *widget.Button // "Fork to edit", shown for read-only gists
+	readOnly             bool           // true for other users' gists
+	dirty                bool           // true if the document has unsaved changes, shown in the window title
+	loading              bool           // true while content is loaded into the fields, which is not a change
+	cfg                  *AppConfig
+	gist                 *github.Gist   // the gist being edited
+	activeFile           string         // filename of the gist file shown in the editor
+	downloads            *downloadQueue // truncated files downloaded in the background, not yet stored to their gist
+}
+
+// downloadQueue holds the full content of truncated gist files, downloaded in the background,
+// until they are stored to their gist where the editor state lives
+type downloadQueue struct {
+	mu    sync.Mutex
+	files []download
+}
+
+// download is the full content of a truncated file of a gist
+type download struct {
+	gist *github.Gist
+	file github.File
 }
 
-// Show displays the editor window
-func (e *Editor) Show() {
-	e.IsVisible = true
-	e.editWindow.Show()
+// newDownloadQueue returns an empty downloadQueue
+func newDownloadQueue() *downloadQueue {
+	return &downloadQueue{}
 }
 
-// Hide hides the editor window
-func (e *Editor) Hide() {
-	e.IsVisible = false
-	e.editWindow.Hide()
+// Add queues a downloaded file
+func (q *downloadQueue) Add(g *github.Gist, f github.File) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.files = append(q.files, download{gist: g, file: f})
+}
+
+// Take returns the queued files, and empties the queue
+func (q *downloadQueue) Take() []download {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	files := q.files
+	q.files = nil
+	return files
 }
 
 // GetContent returns the text contents of the editor
@@ -43,49 +87,291 @@ func (e *Editor) SetContent(text string) {
 	e.editor.SetText(text)
 }
 
+// Cursor returns the row and column of the cursor in the text editor
+func (e *Editor) Cursor() (int, int) {
+	return e.editor.CursorRow, e.editor.CursorColumn
+}
+
+// SetCursor moves the cursor in the text editor, limited to the rows and columns of its text
+func (e *Editor) SetCursor(row int, column int) {
+	lines := strings.Split(e.editor.Text, "\n")
+	row = clamp(row, 0, len(lines)-1)
+	e.editor.CursorRow = row
+	e.editor.CursorColumn = clamp(column, 0, len([]rune(lines[row])))
+	e.editor.Refresh()
+}
+
+// clamp limits n to the range min to max
+func clamp(n int, min int, max int) int {
+	if n > max {
+		n = max
+	}
+	if n < min {
+		n = min
+	}
+	return n
+}
+
+// SetTitle sets the title of the open document, shown in its tab and the window title
+func (e *Editor) SetTitle(title string) {
+	e.Title = title
+	e.updateWindowTitle()
+}
+
+// SetDirty sets whether the document has unsaved changes, which are marked in its tab and the window title
+func (e *Editor) SetDirty(b bool) {
+	e.dirty = b
+	e.updateWindowTitle()
+}
+
+// updateWindowTitle shows the document title in its tab, and in the window title if
+// its tab is active, marked with * if it has unsaved changes
+func (e *Editor) updateWindowTitle() {
+	label := e.Title
+	if e.dirty {
+		label = "*" + label
+	}
+	if e.tab != nil && e.tab.Text != label {
+		e.tab.Text = label
+		e.cfg.EditWindow.tabs.Refresh()
+	}
+	if e.cfg.Editor != e {
+		return
+	}
+	if e.gist == nil {
+		e.editWindow.SetTitle("Edit Gist")
+		return
+	}
+	e.editWindow.SetTitle(label + " - Edit Gist")
+}
+
+// setText shows text in the text editor, without marking the document as changed
+func (e *Editor) setText(text string) {
+	e.loading = true
+	defer func() { e.loading = false }()
+	e.editor.SetText(text)
+	e.editor.ResetHistory() // loaded text is not a change to undo
+}
+
+// changed marks the document as having unsaved changes, unless content is being loaded
+func (e *Editor) changed() {
+	if e.loading || e.gist == nil || e.readOnly {
+		return
+	}
+	e.cfg.setDirty(true)
+	if t := e.cfg.EditWindow.tabOf(e.tab); t != nil {
+		e.cfg.autosaveDue(t)
+	}
+}
+
 // Clear resets the title and contents of the text editor
 func (e *Editor) Clear() {
 	e.Title = "Edit"
-	e.editor.SetText("")
+	e.gist = nil
+	e.dirty = false
+	e.updateWindowTitle()
+	e.activeFile = ""
+	e.setText("")
+	e.SetReadOnly(false)
+	e.progress.Hide()
+	e.fileBar.Refresh()
+	e.metadata.Refresh()
+	e.comments.SetGist("")
+	e.actions.SetGist(nil)
+}
+
+// SetGist loads a gist into the editor, showing its first file.
+// If editableFiles is false, files can't be added, renamed or removed, and the
+// gist metadata is hidden.
+func (e *Editor) SetGist(g *github.Gist, editableFiles bool) {
+	e.gist = g
+	e.dirty = false
+	e.activeFile = ""
+	e.SetReadOnly(false)
+	e.fileBar.SetEditable(editableFiles)
+	e.metadata.SetVisible(editableFiles)
+	e.metadata.Refresh()
+	e.SelectFile(g.Title())
+	e.comments.SetGist(g.ID)
+	e.actions.SetGist(g)
+}
+
+// ReloadGist replaces the gist in the editor, for example after saving,
+// keeping the active file if it still exists.
+func (e *Editor) ReloadGist(g *github.Gist) {
+	if e.gist == nil || e.gist.ID != g.ID {
+		e.comments.SetGist(g.ID) // a new gist was created
+		e.actions.SetGist(g)
+	}
+	active := e.activeFile
+	e.gist = g
+	e.activeFile = ""
+	if g.FileIndex(active) < 0 {
+		active = g.Title()
+	}
+	e.metadata.Refresh()
+	e.SelectFile(active)
+}
+
+// UpdateGist replaces the gist in the editor with a saved version of the same content,
+// keeping the editor text and its undo history. Text typed since the save is stored to the new gist.
+func (e *Editor) UpdateGist(g *github.Gist) {
+	if g.FileIndex(e.activeFile) < 0 {
+		e.ReloadGist(g)
+		return
+	}
+	if e.gist == nil || e.gist.ID != g.ID {
+		e.comments.SetGist(g.ID) // a new gist was created
+		e.actions.SetGist(g)
+	}
+	e.gist = g
+	e.SyncContent()
+	e.metadata.Refresh()
+	e.fileBar.Refresh()
+}
+
+// SelectFile stores the editor content to the active file, and shows the named file
+func (e *Editor) SelectFile(name string) {
+	e.SyncContent()
+	e.activeFile = name
+	if f := e.gist.File(name); f != nil {
+		e.setText(f.Content)
+		if f.Truncated {
+			e.loadFile(name)
+		} else {
+			e.progress.Hide()
+			e.setEditorEnabled()
+		}
+	}
+	e.fileBar.Refresh()
+}
+
+// loadFile downloads the full content of a truncated file in the background,
+// showing the download progress. The editor is read-only until the file is loaded.
+// The content is stored to the gist by SyncContent.
+func (e *Editor) loadFile(name string) {
+	g := e.gist
+	f := *g.File(name)
+	e.editor.Disable()
+	e.progress.SetValue(0)
+	e.progress.Show()
+	go func() {
+		err := e.cfg.GithubClient().LoadFile(&f, func(read, total int) {
+			if total > 0 {
+				e.progress.SetValue(float64(read) / float64(total))
+			}
+		})
+		if e.gist == g && e.activeFile == name {
+			e.progress.Hide()
+			if err != nil {
+				logger.Error("load file failed", err)
+				dialog.ShowError(fmt.Errorf("loading the full file failed, only part of it is shown: %w", err), e.editWindow)
+				return
+			}
+			e.setText(f.Content)
+			e.setEditorEnabled()
+		}
+		if err == nil {
+			// Queued after the text is shown, so that the partial text isn't stored as the full content
+			e.downloads.Add(g, f)
+		}
+	}()
+}
+
+// storeDownloads stores the truncated files downloaded in the background to their gist,
+// if it is still open in the editor
+func (e *Editor) storeDownloads() {
+	for _, d := range e.downloads.Take() {
+		if d.gist != e.gist {
+			continue
+		}
+		if x := d.gist.File(d.file.Filename); x != nil && x.Truncated {
+			x.Content, x.Size, x.Truncated = d.file.Content, d.file.Size, false
+		}
+	}
+}
+
+// SetReadOnly toggles read-only mode, for viewing other users' gists.
+// Read-only gists can't be edited or saved, and can be forked to edit them.
+func (e *Editor) SetReadOnly(b bool) {
+	e.readOnly = b
+	e.setEditorEnabled()
+	if b {
+		e.fileBar.SetEditable(false)
+		e.metadata.SetVisible(false)
+		e.saveButton.Disable()
+		e.forkButton.Show()
+		return
+	}
+	e.saveButton.Enable()
+	e.forkButton.Hide()
+}
+
+// setEditorEnabled enables the text editor, unless the gist is read-only
+func (e *Editor) setEditorEnabled() {
+	if e.readOnly {
+		e.editor.Disable()
+	} else {
+		e.editor.Enable()
+	}
+}
+
+// SyncContent stores the editor content to the active file of the gist.
+// Partially loaded files are read-only, and are not updated.
+func (e *Editor) SyncContent() {
+	if e.gist == nil {
+		return
+	}
+	e.storeDownloads()
+	if f := e.gist.File(e.activeFile); f != nil && !f.Truncated {
+		f.Content = e.editor.Text
+	}
+}
+
+// SetWrapping sets how long lines are wrapped in the text editor
+func (e *Editor) SetWrapping(w fyne.TextWrap) {
+	e.editor.Wrapping = w
+	e.editor.Refresh()
 }
 
 // Undo performs an undo operation on the text editor content
 func (e *Editor) Undo() {
-	// TODO
+	e.editor.Undo()
 }
 
 // Redo performs an redo operation on the text editor content
 func (e *Editor) Redo() {
-	// TODO
+	e.editor.Redo()
 }
 
-// New creates a new Editor window and text editor widget
-func (e Editor) New(cfg *AppConfig) *Editor {
-	a := *cfg.App
-	w := a.NewWindow("Edit Gist")
-	f := cfg.CurrentFile
-	w.Resize(fyne.NewSize(800, 600))
-
-	content, editor, previewEditContainer := editUI(cfg, f.Gist, w)
-	w.SetContent(content)
-	w.CenterOnScreen()
-
-	return &Editor{
-		editor:               editor,
-		editWindow:           w,
-		previewEditContainer: previewEditContainer,
-	}
+// New creates a new Editor and text editor widget, for a tab of the edit window w
+func (e Editor) New(cfg *AppConfig, w fyne.Window) *Editor {
+	ed := &Editor{editWindow: w, cfg: cfg, Title: "Edit", downloads: newDownloadQueue()}
+	ed.fileBar = FileBar{}.New(ed)
+	ed.metadata = MetadataBar{}.New(ed)
+	ed.comments = CommentsPane{}.New(cfg, w)
+	ed.actions = GistActionsBar{}.New(cfg, w)
+	content, editor, previewEditContainer := editUI(cfg, &github.Gist{}, ed, w)
+	ed.content = content
+	ed.editor = editor
+	ed.previewEditContainer = previewEditContainer
+	ed.SetWrapping(wrapMode(cfg.Settings.Preferences.WrapMode))
+	return ed
 }
 
 // Generates the UI for the edit window
 // Returns the container, and a pointer to the content editor, and a wrapper for the single-pane and split-pane containers,
-func editUI(cfg *AppConfig, g *github.Gist, w fyne.Window) (*fyne.Container, *editor.MultiLineWidget, *PreviewEditContainer) {
+func editUI(cfg *AppConfig, g *github.Gist, ed *Editor, w fyne.Window) (*fyne.Container, *editor.MultiLineWidget, *PreviewEditContainer) {
+	comments := ed.comments
 
-	// Title
-	titleBox := TitleBox(g.Filename)
+	// Title, gist actions, metadata and file selector
+	titleRow := container.NewBorder(nil, nil, nil, ed.actions.Content, TitleBox(g.Title()))
+	ed.progress = widget.NewProgressBar()
+	ed.progress.Hide()
+	titleBox := container.NewVBox(titleRow, ed.metadata.Content, ed.fileBar.Content, ed.progress)
 
 	// Editor entry widget -- this is a custom widget that extends fyne's widget.Entry
-	e := editor.NewMultilineWidget(g.Content)
+	e := editor.NewMultilineWidget("")
 
 	// Text editor toolbar
 	textEditorToolbar := editor.New(e)
@@ -97,24 +383,33 @@ func editUI(cfg *AppConfig, g *github.Gist, w fyne.Window) (*fyne.Container, *ed
 	// Preview pane
 	preview := widget.NewRichTextFromMarkdown(e.Text)
 	previewPane := container.NewBorder(widget.NewLabel("Preview"), nil, nil, nil, preview)
-	e.OnChanged = preview.ParseMarkdown // parse markdown to rich text on changed
+	e.OnChanged = func(s string) {
+		preview.ParseMarkdown(s) // parse markdown to rich text on changed
+		e.Record()
+		ed.changed()
+	}
 
 	// Preview and edit pane wrapper
 	previewEditContainer := PreviewEditContainer{}.New(previewPane, editPane)
 
+	// Comments pane, to the right of the preview and edit panes
+	commentsSplit := container.NewHSplit(previewEditContainer.Content, comments.Content)
+	comments.attach(cfg, commentsSplit)
+
 	// Buttons
 	spacer := layout.NewSpacer()
-	saveButton := widget.NewButton("Save", func() {
+	ed.saveButton = widget.NewButton("Save", func() {
 		cfg.SaveFile()
 	})
-	closeButton := widget.NewButton("Close", func() {
-		cfg.CloseFile()
-		cfg.Editor.Hide()
+	ed.forkButton = widget.NewButtonWithIcon("Fork to edit", icons.ToolbarIcons.FileCopyIcon, func() {
+		cfg.ForkGist(ed.gist.ID)
 	})
-	buttons := ButtonContainer(4, spacer, previewEditContainer.ToggleButton, saveButton, closeButton)
+	ed.forkButton.Hide()
+	closeButton := widget.NewButton("Close", cfg.CloseFile)
+	buttons := ButtonContainer(5, spacer, previewEditContainer.ToggleButton, comments.ToggleButton, ed.forkButton, ed.saveButton, closeButton)
 
 	// Wrapper container
-	content := container.NewBorder(titleBox, buttons, nil, nil, previewEditContainer.Content)
+	content := container.NewBorder(titleBox, buttons, nil, nil, commentsSplit)
 	return content, e, previewEditContainer
 }
 
@@ -158,6 +453,17 @@ func (p *PreviewEditContainer) PreviewIsVisible() bool {
 	return p.previewPane.Visible() && p.Content.Offset < 0.9 // Assume a sane default of >10% visibility means it's visible.
 }
 
+// SetPreview shows or hides the markdown preview pane. A visible preview is shown
+// with the divider at offset, or at the middle if offset is not a sane position.
+func (p *PreviewEditContainer) SetPreview(visible bool, offset float64) {
+	if p.PreviewIsVisible() != visible {
+		p.TogglePreview()
+	}
+	if visible && offset > 0.1 && offset < 0.9 {
+		p.Content.SetOffset(offset)
+	}
+}
+
 // TogglePreview toggles the visiblility of the markdown preview pane.
 func (p *PreviewEditContainer) TogglePreview() {
 	// The visiblility of the markdown preview pane is determined by the offset
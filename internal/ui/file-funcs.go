@@ -2,47 +2,183 @@
 package ui
 
 import (
+	"errors"
+	"fmt"
 	"io"
-	"path"
+	"os"
+	"path/filepath"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/storage"
+	"github.com/fieldse/gist-editor/internal/gitgist"
 	"github.com/fieldse/gist-editor/internal/github"
 	"github.com/fieldse/gist-editor/internal/logger"
 )
 
 // A GistFile represents a currently open local or remote markdown file
 type GistFile struct {
-	Gist      *github.Gist
-	isLocal   bool   // true if this is a local file from disk
-	localURI  string // path to file resource: may differ on different OSs
-	isOpen    bool
-	isDirty   bool
-	lastSaved time.Time
+	Gist        *github.Gist
+	isLocal     bool   // true if this is a local file from disk
+	localURI    string // path to file resource: may differ on different OSs
+	isOpen      bool
+	isDirty     bool
+	dirtySince  time.Time // when the document last went from saved to having unsaved changes
+	saving      bool      // true while the document is being saved in the background
+	readOnly    bool      // true for other users' gists, which can't be saved
+	lastSaved   time.Time
+	repo        *gitgist.Repo // the local git clone of the gist, when editing in git mode
+	journalID   string        // the ID of the document's recovery journal entry, assigned when first written
+	journaled   bool          // whether the document may have an entry in the recovery journal
+	diskContent string        // the content of a local file when last loaded or saved, to detect changes by other programs
+	autosaveErr bool          // true once a failed autosave has been reported, until a save succeeds
 }
 
-// Save saves a Gist file to local storage
-func (g *GistFile) Save() {
-	logger.Debug("todo -- save file...")
+// Save writes a local file to its storage URI
+func (g *GistFile) Save() error {
+	if g.localURI == "" {
+		return fmt.Errorf("the file has not been saved before: use Save as")
+	}
+	uri, err := storage.ParseURI(g.localURI)
+	if err != nil {
+		return fmt.Errorf("invalid file location %s: %w", g.localURI, err)
+	}
+	content := g.content()
+	if err := writeURI(uri, []byte(content)); err != nil {
+		return err
+	}
+	g.diskContent = content
 	g.lastSaved = time.Now()
 	g.isDirty = false
+	logger.Info("saved file %s", uri)
+	return nil
 }
 
-// SaveAs saves a Gist file to local storage with a new filename
-func (g *GistFile) SaveAs() {
-	logger.Debug("todo -- save as...")
+// SaveAs writes a local file through the writer of a new storage URI, such as the one
+// from a file save dialog, which has already created the file. The URI becomes its location,
+// and the file takes its name.
+func (g *GistFile) SaveAs(w fyne.URIWriteCloser) error {
+	uri := w.URI()
+	content := g.content()
+	if err := writeTo(w, []byte(content)); err != nil {
+		return err
+	}
+	g.diskContent = content
+	if old := g.Gist.Title(); old != uri.Name() {
+		if err := g.Gist.RenameFile(old, uri.Name()); err != nil {
+			return err
+		}
+	}
+	g.localURI = uri.String()
+	g.isLocal = true
 	g.lastSaved = time.Now()
 	g.isDirty = false
+	logger.Info("saved file as %s", uri)
+	return nil
+}
+
+// content returns the text of a local file. Local files hold a single file.
+func (g *GistFile) content() string {
+	if len(g.Gist.Files) == 0 {
+		return ""
+	}
+	return g.Gist.Files[0].Content
+}
+
+// readURI returns the content of a storage URI, and the parsed URI
+func readURI(s string) ([]byte, fyne.URI, error) {
+	uri, err := storage.ParseURI(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := storage.Reader(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return data, uri, err
+}
+
+// writeURI writes data to a storage URI. Local files are written atomically,
+// keeping their permissions; other URIs are written through Fyne storage.
+func writeURI(uri fyne.URI, data []byte) error {
+	if uri.Scheme() == "file" {
+		return writeFileAtomic(uri.Path(), data)
+	}
+	w, err := storage.Writer(uri)
+	if err != nil {
+		return fmt.Errorf("open %s for writing: %w", uri, err)
+	}
+	return writeTo(w, data)
+}
+
+// writeTo writes data through a storage writer, and closes it
+func writeTo(w fyne.URIWriteCloser, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("write %s: %w", w.URI(), err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", w.URI(), err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory, then
+// renames it over the target, so the file is never left partly written.
+// The permissions of an existing file are kept; new files are created with mode 0644.
+// If the target is a symlink, the file it points to is replaced.
+func writeFileAtomic(fp string, data []byte) error {
+	mode := os.FileMode(0644)
+	if resolved, err := filepath.EvalSymlinks(fp); err == nil {
+		fp = resolved
+	}
+	info, err := os.Stat(fp)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("stat %s: %w", fp, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fp), "."+filepath.Base(fp)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", fp, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", fp, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", fp, err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("set permissions of %s: %w", fp, err)
+	}
+	if err := os.Rename(tmp.Name(), fp); err != nil {
+		return fmt.Errorf("replace %s: %w", fp, err)
+	}
+	return nil
 }
 
 // Close clears a Gist file to empty and marks as closed
 func (g *GistFile) Close() {
 	g.Gist = &github.Gist{}
+	g.repo = nil
 	g.isOpen = false
 	g.isLocal = false
+	g.readOnly = false
 	g.isDirty = false
+	g.journalID = ""
+	g.journaled = false
+	g.diskContent = ""
 }
 
 // Openable filetypes  filter
@@ -67,23 +203,33 @@ func openFile(read fyne.URIReadCloser, err error) {
 		dialog.ShowError(err, w)
 		return
 	}
-	filePath := read.URI().Path()
-	fileName := read.URI().Name()
+	cfg.showLocalFile(read.URI(), data)
+}
+
+// showLocalFile opens a local file in a new tab, or shows its tab if it is already open
+func (cfg *AppConfig) showLocalFile(uri fyne.URI, data []byte) {
+	if cfg.showTab(func(f *GistFile) bool { return f.isLocal && f.localURI == uri.String() }) {
+		return
+	}
+	fileName := uri.Name()
 
 	logger.Debug("open file succeeded: filename: %s", fileName)
 
 	// Initialize a new Gist from the data
 	g := github.Gist{}.New(fileName, string(data))
-	cfg.CurrentFile = &GistFile{
-		Gist:     &g,
-		isLocal:  true,
-		isOpen:   true,
-		localURI: path.Join(filePath, fileName),
-	}
+	cfg.openTab(&GistFile{
+		Gist:        &g,
+		isLocal:     true,
+		isOpen:      true,
+		localURI:    uri.String(),
+		diskContent: string(data),
+	})
 
-	// Update the content of the editor window
-	cfg.Editor.SetContent(g.Content)
-	cfg.Editor.Title = fileName
+	// Update the content of the editor window. Local files contain a single file.
+	cfg.Editor.SetGist(cfg.CurrentFile.Gist, false)
+	cfg.Editor.SetTitle(fileName)
+	cfg.watchFiles()
+	cfg.addRecent()
 
 	// Show the edit window
 	cfg.MainWindow.SetCanSave(true)
@@ -0,0 +1,123 @@
+// File selector bar for switching between, adding, renaming and removing the files of a gist
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// FileBar is the file selector and file actions toolbar of the editor window
+type FileBar struct {
+	Content      *fyne.Container
+	selector     *widget.Select
+	addButton    *widget.Button
+	renameButton *widget.Button
+	removeButton *widget.Button
+	editor       *Editor
+}
+
+// New returns a new FileBar for the given editor
+func (f FileBar) New(e *Editor) *FileBar {
+	fb := &FileBar{editor: e}
+	fb.selector = widget.NewSelect([]string{}, func(name string) {
+		if name != "" && name != e.activeFile {
+			e.SelectFile(name)
+		}
+	})
+	fb.addButton = widget.NewButton("Add file", fb.showAddDialog)
+	fb.renameButton = widget.NewButton("Rename", fb.showRenameDialog)
+	fb.removeButton = widget.NewButton("Delete", fb.showRemoveDialog)
+	buttons := container.NewHBox(fb.addButton, fb.renameButton, fb.removeButton)
+	fb.Content = container.NewBorder(nil, nil, widget.NewLabel("File"), buttons, fb.selector)
+	return fb
+}
+
+// Refresh updates the file selector options from the editor's gist
+func (f *FileBar) Refresh() {
+	if f.editor.gist == nil {
+		f.selector.Options = []string{}
+		f.selector.ClearSelected()
+		return
+	}
+	f.selector.Options = f.editor.gist.Filenames()
+	f.selector.SetSelected(f.editor.activeFile)
+	f.selector.Refresh()
+}
+
+// SetEditable toggles whether files can be added, renamed and removed
+func (f *FileBar) SetEditable(b bool) {
+	for _, btn := range []*widget.Button{f.addButton, f.renameButton, f.removeButton} {
+		if b {
+			btn.Enable()
+		} else {
+			btn.Disable()
+		}
+	}
+}
+
+// showAddDialog asks for a filename, and adds a new empty file to the gist
+func (f *FileBar) showAddDialog() {
+	e := f.editor
+	input := widget.NewEntry()
+	input.PlaceHolder = "filename.md"
+	items := []*widget.FormItem{widget.NewFormItem("Filename", input)}
+	dialog.ShowForm("Add file", "Add", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := e.gist.AddFile(input.Text, ""); err != nil {
+			dialog.ShowError(fmt.Errorf("add file failed: %w", err), e.editWindow)
+			return
+		}
+		logger.Debug("added file %s", input.Text)
+		e.SelectFile(input.Text)
+		e.changed()
+	}, e.editWindow)
+}
+
+// showRenameDialog asks for a new filename for the active file
+func (f *FileBar) showRenameDialog() {
+	e := f.editor
+	oldName := e.activeFile
+	input := widget.NewEntry()
+	input.SetText(oldName)
+	items := []*widget.FormItem{widget.NewFormItem("New filename", input)}
+	dialog.ShowForm("Rename file", "Rename", "Cancel", items, func(ok bool) {
+		if !ok || input.Text == oldName {
+			return
+		}
+		if err := e.gist.RenameFile(oldName, input.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("rename file failed: %w", err), e.editWindow)
+			return
+		}
+		logger.Debug("renamed file %s to %s", oldName, input.Text)
+		e.activeFile = input.Text
+		f.Refresh()
+		e.changed()
+	}, e.editWindow)
+}
+
+// showRemoveDialog asks for confirmation, and removes the active file from the gist
+func (f *FileBar) showRemoveDialog() {
+	e := f.editor
+	name := e.activeFile
+	msg := fmt.Sprintf("Delete %s from this gist?\nThe file is removed on Github when the gist is saved.", name)
+	dialog.ShowConfirm("Delete file", msg, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := e.gist.RemoveFile(name); err != nil {
+			dialog.ShowError(fmt.Errorf("delete file failed: %w", err), e.editWindow)
+			return
+		}
+		logger.Debug("removed file %s", name)
+		e.activeFile = ""
+		e.SelectFile(e.gist.Title())
+		e.changed()
+	}, e.editWindow)
+}
@@ -0,0 +1,164 @@
+// Gist actions bar for the editor: star, fork, forks, history and git mode
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/icons"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// GistActionsBar holds the actions for a gist saved on Github
+type GistActionsBar struct {
+	Content       *fyne.Container
+	starButton    *widget.Button
+	forkButton    *widget.Button
+	forksButton   *widget.Button
+	historyButton *widget.Button
+	cloneButton   *widget.Button // switches to git mode
+	pullButton    *widget.Button // git mode only
+	attachButton  *widget.Button // git mode only
+	gistID        string
+	starred       bool
+	gitMode       bool // true once the gist is cloned
+	window        fyne.Window
+	cfg           *AppConfig
+}
+
+// New returns a new GistActionsBar, with all actions disabled until a gist is set
+func (b GistActionsBar) New(cfg *AppConfig, w fyne.Window) *GistActionsBar {
+	ab := &GistActionsBar{window: w, cfg: cfg}
+	ab.starButton = widget.NewButtonWithIcon("Star", icons.ToolbarIcons.FolderStarIcon, func() { ab.toggleStar(cfg) })
+	ab.forkButton = widget.NewButtonWithIcon("Fork", icons.ToolbarIcons.FileCopyIcon, func() { cfg.ForkGist(ab.gistID) })
+	ab.forksButton = widget.NewButton("Forks", func() { ab.showForks(cfg) })
+	ab.historyButton = widget.NewButton("History", cfg.ShowHistoryWindow)
+	ab.cloneButton = widget.NewButton("Git clone", cfg.CloneGist)
+	ab.pullButton = widget.NewButton("Pull", cfg.PullGist)
+	ab.attachButton = widget.NewButton("Attach file...", cfg.AttachFile)
+	ab.Content = container.NewHBox(ab.starButton, ab.forkButton, ab.forksButton, ab.historyButton,
+		ab.cloneButton, ab.pullButton, ab.attachButton)
+	ab.SetGist(nil)
+	return ab
+}
+
+// SetGist updates the actions for the given gist.
+// A nil or unsaved gist disables all actions. Forking is only enabled for other users' gists,
+// and cloning for the user's own gists.
+func (b *GistActionsBar) SetGist(g *github.Gist) {
+	cfg := b.cfg
+	all := []*widget.Button{b.starButton, b.forkButton, b.forksButton, b.historyButton, b.cloneButton, b.pullButton, b.attachButton}
+	for _, btn := range all {
+		btn.Disable()
+	}
+	b.starred = false
+	b.starButton.SetText("Star")
+	if g == nil || g.IsNew() || g.ID != b.gistID {
+		b.gitMode = false
+	}
+	if g == nil || g.IsNew() {
+		b.gistID = ""
+		return
+	}
+	b.gistID = g.ID
+	b.forksButton.Enable()
+	b.historyButton.Enable()
+
+	// Load the ownership and star status in the background
+	id, owner, gitMode := g.ID, g.AuthorId, b.gitMode
+	go func() {
+		login := cfg.githubLogin()
+		starred, err := cfg.GithubClient().IsStarred(id)
+		if id != b.gistID {
+			return // a different gist was opened while loading
+		}
+		if owner != "" && owner != login {
+			b.forkButton.Enable()
+		} else if !gitMode {
+			b.cloneButton.Enable()
+		}
+		if err != nil {
+			logger.Error("get star status failed", err)
+			return
+		}
+		b.setStarred(starred)
+		b.starButton.Enable()
+	}()
+}
+
+// SetGitMode toggles the git mode actions, after the gist is cloned
+func (b *GistActionsBar) SetGitMode(on bool) {
+	b.gitMode = on
+	if on {
+		b.cloneButton.Disable()
+		b.pullButton.Enable()
+		b.attachButton.Enable()
+		return
+	}
+	b.pullButton.Disable()
+	b.attachButton.Disable()
+}
+
+// setStarred updates the star button for the given star status
+func (b *GistActionsBar) setStarred(starred bool) {
+	b.starred = starred
+	if starred {
+		b.starButton.SetText("Unstar")
+	} else {
+		b.starButton.SetText("Star")
+	}
+}
+
+// toggleStar stars or unstars the gist in the background
+func (b *GistActionsBar) toggleStar(cfg *AppConfig) {
+	id, starred := b.gistID, b.starred
+	b.starButton.Disable()
+	go func() {
+		var err error
+		if starred {
+			err = cfg.GithubClient().UnstarGist(id)
+		} else {
+			err = cfg.GithubClient().StarGist(id)
+		}
+		if id != b.gistID {
+			return // a different gist was opened while saving
+		}
+		b.starButton.Enable()
+		if err != nil {
+			logger.Error("star gist failed", err)
+			dialog.ShowError(fmt.Errorf("updating star failed: %w", err), b.window)
+			return
+		}
+		b.setStarred(!starred)
+	}()
+}
+
+// showForks shows a dialog listing the forks of the gist, loaded in the background.
+// Selecting a fork opens it.
+func (b *GistActionsBar) showForks(cfg *AppConfig) {
+	id := b.gistID
+	go func() {
+		forks, err := cfg.GithubClient().ListForks(id)
+		if err != nil {
+			logger.Error("list forks failed", err)
+			dialog.ShowError(fmt.Errorf("loading forks failed: %w", err), b.window)
+			return
+		}
+		if len(forks) == 0 {
+			dialog.ShowInformation("Forks", "This gist has no forks.", b.window)
+			return
+		}
+		var d dialog.Dialog
+		l := newList(func() []github.Gist { return forks }, func(g github.Gist) {
+			d.Hide()
+			cfg.OpenGist(g.ID)
+		}, true)
+		d = dialog.NewCustom("Forks", "Close", l, b.window)
+		d.Resize(fyne.NewSize(400, 300))
+		d.Show()
+	}()
+}
@@ -0,0 +1,234 @@
+// Paginated gist list, loaded lazily as the user scrolls
+package ui
+
+import (
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// Number of gists to load per page
+const gistPageSize = 50
+
+// Load the next page when the list is scrolled to within this many items of the end
+const loadMoreThreshold = 10
+
+// gistSection is a list of gists loaded from Github one page at a time.
+// The next page is loaded when the user scrolls near the end of the list, and
+// refreshes only fetch gists updated since the last load.
+type gistSection struct {
+	list         *widget.List
+	gists        []github.Gist
+	nextPage     int  // the next page to load, or 0 when all pages are loaded
+	pages        int  // the number of pages loaded
+	loaded       bool // true once the first page has been loaded
+	loading      bool
+	generation   int // incremented by Reset, so that loads started before it are discarded
+	mu           sync.Mutex
+	fetchPage    func(github.ListOptions) (github.GistPage, error)
+	fetchUpdated func(since time.Time) ([]github.Gist, error) // may be nil, if incremental refresh isn't supported
+	onError      func(error)
+}
+
+// newGistSection returns a new, empty gistSection.
+// onSelect is called with the selected Gist. If showOwner is true, the owner of each gist is shown.
+func newGistSection(
+	fetchPage func(github.ListOptions) (github.GistPage, error),
+	fetchUpdated func(since time.Time) ([]github.Gist, error),
+	onSelect func(github.Gist),
+	showOwner bool,
+	onError func(error),
+) *gistSection {
+	s := &gistSection{
+		gists:        []github.Gist{},
+		fetchPage:    fetchPage,
+		fetchUpdated: fetchUpdated,
+		onError:      onError,
+	}
+	s.list = newList(s.data, onSelect, showOwner)
+
+	// Load the next page when an item near the end of the list is shown
+	update := s.list.UpdateItem
+	s.list.UpdateItem = func(i widget.ListItemID, o fyne.CanvasObject) {
+		update(i, o)
+		if i >= len(s.data())-loadMoreThreshold {
+			s.LoadMore()
+		}
+	}
+	return s
+}
+
+// data returns the loaded gists
+func (s *gistSection) data() []github.Gist {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gists
+}
+
+// Set replaces the list data. No further pages are loaded.
+func (s *gistSection) Set(gists []github.Gist) {
+	s.mu.Lock()
+	s.gists = gists
+	s.nextPage = 0
+	s.pages = 1
+	s.loaded = true
+	s.mu.Unlock()
+	s.list.UnselectAll()
+	s.list.Refresh()
+}
+
+// Reset clears the list, so that the next Refresh reloads from the first page.
+// Loads in progress are discarded when they complete.
+func (s *gistSection) Reset() {
+	s.mu.Lock()
+	s.gists = []github.Gist{}
+	s.nextPage = 0
+	s.pages = 0
+	s.loaded = false
+	s.loading = false
+	s.generation++
+	s.mu.Unlock()
+	s.list.UnselectAll()
+	s.list.Refresh()
+}
+
+// Refresh loads the first page if the list hasn't been loaded yet.
+// Otherwise, only gists updated since the last load are fetched, and merged into the list.
+func (s *gistSection) Refresh() {
+	s.mu.Lock()
+	loaded := s.loaded
+	s.mu.Unlock()
+	if !loaded || s.fetchUpdated == nil {
+		s.Reload()
+		return
+	}
+	gen, ok := s.startLoading()
+	if !ok {
+		return
+	}
+	go func() {
+		defer s.stopLoading(gen)
+		since := github.LatestUpdate(s.data())
+		updated, err := s.fetchUpdated(since)
+		if err != nil {
+			s.fail(gen, err)
+			return
+		}
+		logger.Debug("refresh gists: %d updated since %s", len(updated), since.Format(time.RFC3339))
+		s.mu.Lock()
+		if gen == s.generation {
+			s.gists = github.MergeGists(s.gists, updated)
+		}
+		s.mu.Unlock()
+		s.list.Refresh()
+	}()
+}
+
+// Reload replaces the list with the pages of gists loaded so far, or the first page,
+// loaded again in full. Gists that are no longer listed, such as deleted or unstarred
+// gists, are removed.
+func (s *gistSection) Reload() {
+	gen, ok := s.startLoading()
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	pages := s.pages
+	s.mu.Unlock()
+	if pages < 1 {
+		pages = 1
+	}
+	go func() {
+		defer s.stopLoading(gen)
+		var gists []github.Gist
+		next, loaded := 1, 0
+		for next != 0 && loaded < pages {
+			page, err := s.fetchPage(github.ListOptions{Page: next, PerPage: gistPageSize})
+			if err != nil {
+				s.fail(gen, err)
+				return
+			}
+			gists = github.MergeGists(gists, page.Gists)
+			next = page.NextPage
+			loaded++
+		}
+		s.mu.Lock()
+		if gen != s.generation {
+			s.mu.Unlock()
+			return // reset while loading
+		}
+		s.gists = gists
+		s.nextPage = next
+		s.pages = loaded
+		s.loaded = true
+		s.mu.Unlock()
+		s.list.UnselectAll()
+		s.list.Refresh()
+	}()
+}
+
+// LoadMore appends the next page of gists, if there is one
+func (s *gistSection) LoadMore() {
+	s.mu.Lock()
+	next := s.nextPage
+	s.mu.Unlock()
+	if next == 0 {
+		return
+	}
+	gen, ok := s.startLoading()
+	if !ok {
+		return
+	}
+	go func() {
+		defer s.stopLoading(gen)
+		page, err := s.fetchPage(github.ListOptions{Page: next, PerPage: gistPageSize})
+		if err != nil {
+			s.fail(gen, err)
+			return
+		}
+		s.mu.Lock()
+		if gen == s.generation {
+			s.gists = github.MergeGists(s.gists, page.Gists)
+			s.nextPage = page.NextPage
+			s.pages++
+		}
+		s.mu.Unlock()
+		s.list.Refresh()
+	}()
+}
+
+// startLoading marks the section as loading, and returns the generation of the load.
+// Returns false if a load is already in progress.
+func (s *gistSection) startLoading() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loading {
+		return 0, false
+	}
+	s.loading = true
+	return s.generation, true
+}
+
+// stopLoading marks the section as no longer loading, unless it was reset since the load started
+func (s *gistSection) stopLoading(gen int) {
+	s.mu.Lock()
+	if gen == s.generation {
+		s.loading = false
+	}
+	s.mu.Unlock()
+}
+
+// fail logs and reports a load error, unless the section was reset since the load started
+func (s *gistSection) fail(gen int, err error) {
+	logger.Error("load gists failed", err)
+	s.mu.Lock()
+	stale := gen != s.generation
+	s.mu.Unlock()
+	if !stale && s.onError != nil {
+		s.onError(err)
+	}
+}
@@ -1,57 +1,101 @@
-// Modal for configuring the Github API token
+// Modal for configuring the Github API token and host
 package ui
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
-	"os/user"
 	"path"
 	"regexp"
+	"strings"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/config"
+	"github.com/fieldse/gist-editor/internal/github"
 	"github.com/fieldse/gist-editor/internal/logger"
+	"github.com/fieldse/gist-editor/internal/secrets"
+	"github.com/fieldse/gist-editor/internal/tokensource"
 )
 
-// Path under the user's home directory for storing app configuration
-var CONFIG_DIR_NAME = "./gist-editor"                                    // FIXME: should not be hardcoded
-var GITHUB_CONFIG_FILE = path.Join(userConfigPath(), "github-token.txt") // FIXME: Improve file format
+// ConfigDir is the app config directory. If empty, $XDG_CONFIG_HOME/gist-editor is used.
+var ConfigDir = ""
+
+// Config directory of earlier versions of the app, migrated on load
+var legacyConfigDir = config.LegacyDir()
+
+// Legacy plain text token file, migrated to the secret store
+var GITHUB_CONFIG_FILE = legacyFile("github-token.txt")
 
 // GithubSettingsWindow is the the Github config settings window
 type GithubSettingsWindow struct {
-	dialog     *dialog.FormDialog // the main form
-	tokenField *widget.Entry      // the Github token field
+	dialog      *dialog.FormDialog // the main form
+	tokenField  *widget.Entry      // the Github token field
+	apiURLField *widget.Entry      // the API base URL field, for Github Enterprise Server
+	webURLField *widget.Entry      // the web host URL field, for Github Enterprise Server
+	clientField *widget.Entry      // the OAuth app client ID field, for sign-in
+	sourceLabel *widget.Label      // where the active token was found
+	loadedToken string             // the token read from the token sources, to tell whether the user typed a new one
+	signIn      *widget.Button     // signs in with the OAuth device flow
+	signInNote  *widget.Label      // explains why sign-in is unavailable
 }
 
 // New returns a new instance of the GithubSettingsWindow
 func (g GithubSettingsWindow) New(cfg *AppConfig) *GithubSettingsWindow {
-	w, entry := githubSettingsUI(cfg)
-	return &GithubSettingsWindow{
-		dialog:     w,
-		tokenField: entry,
-	}
+	gw := &GithubSettingsWindow{}
+	gw.dialog = githubSettingsUI(cfg, gw)
+	return gw
 }
 
-// Load reads the github setting from file, and stores to the app config.
+// Load reads the settings from the config file and the active account's token from
+// the token sources, and stores them to the app config.
 func (g *GithubSettingsWindow) Load(cfg *AppConfig) error {
-	token, err := ReadGithubToken()
+	dir, err := configDir()
 	if err != nil {
-		logger.Error("load Github settings failed", err)
 		return err
 	}
-	if token == "" {
-		logger.Info("no github token found")
-		return nil
+	settings, err := config.Load(dir, legacyConfigDir)
+	if err != nil {
+		logger.Error("load config failed", err)
+		return err
+	}
+	cfg.Settings = &settings
+	if err := cfg.loadAccount(settings.GithubConfig()); err != nil {
+		logger.Error("load Github settings failed", err)
+		return err
 	}
-	logger.Info("github token loaded from %s", GITHUB_CONFIG_FILE)
-	g.tokenField.SetText(token) // update the UI placeholder
-	cfg.GithubConfig.GithubAPIToken = token
 	return nil
 }
 
+// setFields shows the host and token of an account in the settings fields,
+// and where the token was found
+func (g *GithubSettingsWindow) setFields(host github.GithubConfig, tok tokensource.Token) {
+	g.apiURLField.SetText(host.APIBaseURL)
+	g.webURLField.SetText(host.WebBaseURL)
+	g.clientField.SetText(host.OAuthClientID)
+	g.loadedToken = tok.Value
+	g.tokenField.SetText(tok.Value)
+	g.sourceLabel.SetText(tok.Describe())
+	g.setCanSignIn(host)
+}
+
+// setCanSignIn enables the sign-in button if the account has an OAuth app client ID.
+// Otherwise the button is disabled, with an explanation.
+func (g *GithubSettingsWindow) setCanSignIn(host github.GithubConfig) {
+	if host.CanSignIn() {
+		g.signIn.Enable()
+		g.signInNote.Hide()
+		return
+	}
+	g.signIn.Disable()
+	g.signInNote.Show()
+}
+
 // Show shows the Github settings modal
 func (g GithubSettingsWindow) Show() {
 	g.dialog.Show()
@@ -63,87 +107,424 @@ func (g GithubSettingsWindow) Hide() {
 }
 
 // githubSettingsUI generates the form and modal for Githut settings.
-// Returns the form dialog and the Github token entry field.
-func githubSettingsUI(cfg *AppConfig) (*dialog.FormDialog, *widget.Entry) {
+// Returns the form dialog, and stores the entry fields to the settings window.
+func githubSettingsUI(cfg *AppConfig, g *GithubSettingsWindow) *dialog.FormDialog {
 	w := cfg.MainWindow.Window
-	input := widget.NewEntry()
-	input.PlaceHolder = "Enter your Github API token..."
-	var tempVal string = ""
-	input.OnChanged = func(s string) {
-		tempVal = s
-	}
-	input.OnSubmitted = func(s string) {
-		tempVal = s
+
+	// Github Enterprise Server host fields. Empty values use github.com.
+	g.apiURLField = widget.NewEntry()
+	g.apiURLField.PlaceHolder = github.DefaultAPIBaseURL
+	g.webURLField = widget.NewEntry()
+	g.webURLField.PlaceHolder = "derived from the API URL"
+	g.clientField = widget.NewEntry()
+	g.clientField.PlaceHolder = "OAuth app client ID, for sign-in"
+	hostConfig := func() github.GithubConfig {
+		return github.GithubConfig{
+			Name:          cfg.GithubConfig.Name,
+			APIBaseURL:    strings.TrimSpace(g.apiURLField.Text),
+			WebBaseURL:    strings.TrimSpace(g.webURLField.Text),
+			OAuthClientID: strings.TrimSpace(g.clientField.Text),
+		}
 	}
+	g.clientField.OnChanged = func(string) { g.setCanSignIn(hostConfig()) }
+	testResult := widget.NewLabel("")
+	var testButton *widget.Button
+	testButton = widget.NewButton("Test connection", func() {
+		testResult.SetText("Connecting...")
+		testButton.Disable()
+		host := hostConfig()
+		go func() {
+			defer testButton.Enable()
+			msg, err := testConnection(host)
+			if err != nil {
+				testResult.SetText("Connection failed")
+				dialog.ShowError(err, w)
+				return
+			}
+			testResult.SetText(msg)
+		}()
+	})
+
+	input := widget.NewPasswordEntry()
+	input.PlaceHolder = "Enter your Github API token..."
+	g.signIn = widget.NewButton("Sign in with Github", func() {
+		signIn(cfg, g, hostConfig())
+	})
+	g.signInNote = widget.NewLabel("Sign-in needs the client ID of an OAuth app with device flow enabled. Enter it below, or enter a token.")
+	g.signInNote.Wrapping = fyne.TextWrapWord
+	g.setCanSignIn(hostConfig())
 	// Onsave for the form dialog
 	onSave := func(b bool) {
 		if !b { // if the user cancels the dialog
 			return
 		}
-		var originalVal = input.Text
-		err := saveToken(tempVal)
+		host := hostConfig()
+		token := strings.TrimSpace(input.Text)
+		// Tokens read from the environment or the gh CLI are left where they are; only a
+		// token the user typed is saved. Without a token, gists can be browsed anonymously.
+		typed := token != "" && token != g.loadedToken
+
+		// Check the host is reachable, and any new token works and can be used for gists,
+		// before saving either. The checks are network requests, so run in the background.
+		go func() {
+			if _, err := testConnection(host); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			var user github.User
+			if typed {
+				u, err := verifyToken(host, token)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				user = u
+			}
+			if !typed {
+				token = ""
+			}
+			if err := saveGithubSettings(cfg, host, token); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if !typed {
+				dialog.ShowInformation("Github settings saved", "Connected to "+host.APIURL(), w)
+				return
+			}
+			if cfg.GithubConfig.GithubAPIToken == token {
+				cfg.githubUser = &user
+			}
+			logger.Debug("Github API token saved for user %s", user.Login)
+			dialog.ShowInformation("Github token saved", "Signed in as "+user.Login, w)
+		}()
+	}
+	g.tokenField = input
+	g.sourceLabel = widget.NewLabel(tokensource.Token{}.Describe())
+	var formItems []*widget.FormItem
+	formItems = append(formItems, widget.NewFormItem("", container.NewVBox(g.signIn, g.signInNote)))
+	formItems = append(formItems, widget.NewFormItem("Github API token", input))
+	formItems = append(formItems, widget.NewFormItem("Token source", g.sourceLabel))
+	formItems = append(formItems, widget.NewFormItem("API base URL", g.apiURLField))
+	formItems = append(formItems, widget.NewFormItem("Web URL", g.webURLField))
+	formItems = append(formItems, widget.NewFormItem("OAuth client ID", g.clientField))
+	formItems = append(formItems, widget.NewFormItem("", container.NewBorder(nil, nil, testButton, nil, testResult)))
+	d := dialog.NewForm("Github Settings", "Save", "Cancel", formItems, onSave, w)
+	d.Resize(fyne.NewSize(500, 300))
+	return d
+}
+
+// signIn signs in to Github with the OAuth device flow. The user code is requested and
+// shown in a dialog while polling for approval, in the background, and the resulting token is saved.
+func signIn(cfg *AppConfig, g *GithubSettingsWindow, host github.GithubConfig) {
+	w := cfg.MainWindow.Window
+	if err := host.Validate(); err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	flow := host.NewDeviceFlow()
+	ctx, cancel := context.WithCancel(context.Background())
+	content := container.NewVBox()
+	status := widget.NewLabel("Requesting a sign-in code...")
+	content.Add(status)
+	d := dialog.NewCustom("Sign in with Github", "Cancel", content, w)
+	d.SetOnClosed(cancel)
+	d.Show()
+
+	go func() {
+		code, err := flow.RequestCode()
+		if ctx.Err() != nil {
+			logger.Info("sign in cancelled")
+			return
+		}
 		if err != nil {
-			d := dialog.NewError(fmt.Errorf("error saving token: %s", err.Error()), w)
-			d.Show()
-			input.SetText(originalVal) // Reset to original state
+			d.Hide()
+			logger.Error("request device code failed", err)
+			dialog.ShowError(fmt.Errorf("sign in failed: %w", err), w)
+			return
+		}
+		codeField := widget.NewEntry()
+		codeField.SetText(code.UserCode)
+		codeField.TextStyle = fyne.TextStyle{Monospace: true, Bold: true}
+		content.RemoveAll()
+		content.Add(widget.NewLabel("Enter this code on Github to sign in:"))
+		content.Add(codeField)
+		if link, err := url.Parse(code.VerificationURI); err == nil {
+			content.Add(widget.NewHyperlink(code.VerificationURI, link))
+			(*cfg.App).OpenURL(link)
+		}
+		status.SetText("Waiting for approval...")
+		content.Add(status)
+
+		token, err := flow.PollToken(ctx, code)
+		if ctx.Err() != nil {
+			logger.Info("sign in cancelled")
 			return
 		}
-		d := dialog.NewInformation("Github token saved", "Github token updated", w)
-		d.Show()
-		logger.Debug("Github API token saved: %v", tempVal)
-		cfg.GithubConfig.GithubAPIToken = tempVal
+		d.Hide()
+		if err != nil {
+			logger.Error("sign in failed", err)
+			dialog.ShowError(fmt.Errorf("sign in failed: %w", err), w)
+			return
+		}
+		user, err := verifyToken(host, token)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if err := saveHostSettings(cfg, host); err != nil {
+			dialog.ShowError(fmt.Errorf("error saving Github host: %w", err), w)
+			return
+		}
+		if err := saveToken(host.Name, token); err != nil {
+			dialog.ShowError(fmt.Errorf("error saving token: %w", err), w)
+			return
+		}
+		cfg.GithubConfig.APIBaseURL = host.APIBaseURL
+		cfg.GithubConfig.WebBaseURL = host.WebBaseURL
+		cfg.GithubConfig.OAuthClientID = host.OAuthClientID
+		cfg.GithubConfig.GithubAPIToken = token
+		cfg.githubUser = &user
+		cfg.ListWindow.Clear()
+		g.tokenField.SetText(token)
+		g.sourceLabel.SetText(storedToken().Describe())
+		logger.Info("signed in to Github as %s with device flow", user.Login)
+		dialog.ShowInformation("Signed in", "Signed in to Github as "+user.Login, w)
+	}()
+}
+
+// verifyToken checks the token with the Github API, and returns the user it belongs to.
+// Returns an error describing the problem if the token is rejected or lacks the gist scope.
+func verifyToken(host github.GithubConfig, token string) (github.User, error) {
+	if token == "" {
+		return github.User{}, fmt.Errorf("token is empty")
 	}
-	var formItems []*widget.FormItem
-	formItems = append(formItems, widget.NewFormItem("Github API token", input))
-	d := dialog.NewForm("Github API Token", "Save", "Cancel", formItems, onSave, w)
-	d.Resize(fyne.NewSize(400, 200))
-	return d, input
+	host.GithubAPIToken = token
+	info, err := host.NewClient().VerifyToken()
+	switch {
+	case errors.Is(err, github.ErrTokenInvalid):
+		return github.User{}, fmt.Errorf("Github rejected the token: it is invalid, expired or has been revoked. Create a new token, or sign in again")
+	case errors.Is(err, github.ErrMissingScope):
+		return github.User{}, fmt.Errorf("the token for %s is missing the 'gist' scope. Create a token with the gist scope, or sign in again", info.User.Login)
+	case err != nil:
+		logger.Error("verify token failed", err)
+		return github.User{}, fmt.Errorf("could not verify the token: %w", err)
+	}
+	return info.User, nil
 }
 
-// ReadGithubToken reads and returns the Github API token, if it exists.
-func ReadGithubToken() (string, error) {
-	if !fileExists(GITHUB_CONFIG_FILE) {
-		logger.Info("load github settings: no file found.")
+// testConnection validates the host settings, and sends a test request to the API host.
+// Returns a description of the server on success.
+func testConnection(host github.GithubConfig) (string, error) {
+	if err := host.Validate(); err != nil {
+		return "", err
+	}
+	info, err := host.NewClient().Ping()
+	if err != nil {
+		logger.Error("test connection failed", err)
+		return "", fmt.Errorf("could not connect to %s: %w", host.APIURL(), err)
+	}
+	if info.InstalledVersion != "" {
+		return "Connected to Github Enterprise Server " + info.InstalledVersion, nil
+	}
+	return "Connected to " + host.APIURL(), nil
+}
+
+// saveGithubSettings saves the host settings of an account and, if not empty, its token,
+// then reloads the active account. If the token can't be saved, the host settings are restored.
+func saveGithubSettings(cfg *AppConfig, host github.GithubConfig, token string) error {
+	previous := *cfg.Settings
+	if err := saveHostSettings(cfg, host); err != nil {
+		return fmt.Errorf("error saving Github host: %w", err)
+	}
+	if token != "" {
+		if err := saveToken(host.Name, token); err != nil {
+			if err := saveSettings(previous); err != nil {
+				logger.Error("restore settings failed", err)
+			} else {
+				cfg.Settings = &previous
+			}
+			return fmt.Errorf("error saving token: %w", err)
+		}
+	}
+	return cfg.loadAccount(cfg.Settings.GithubConfig())
+}
+
+// saveHostSettings saves the Github host URLs of the account to the config file.
+// If the account has no name, the active account is updated.
+func saveHostSettings(cfg *AppConfig, host github.GithubConfig) error {
+	settings := *cfg.Settings
+	if host.Name == "" {
+		host.Name = settings.GithubConfig().Name
+	}
+	host.GithubAPIToken = ""
+	settings.SetGithubConfig(host)
+	if err := saveSettings(settings); err != nil {
+		return err
+	}
+	cfg.Settings = &settings
+	return nil
+}
+
+// saveSettings writes the settings to the config file
+func saveSettings(settings config.Config) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	return config.Save(dir, settings)
+}
+
+// Key of the Github token in the secret store, before tokens were stored per account
+const githubTokenKey = "github-token"
+
+// tokenKey returns the key of an account's Github token in the secret store
+func tokenKey(account string) string {
+	return githubTokenKey + ":" + account
+}
+
+// tokenStore is the secret store for credentials. Defaults to the platform's
+// store on first use; tests replace it with an in-memory store.
+var tokenStore secrets.Store
+
+// secretStore returns the secret store for credentials
+func secretStore() secrets.Store {
+	if tokenStore == nil {
+		dir, err := configDir()
+		if err != nil {
+			logger.Error("no config dir for secrets: credentials will not be saved", err)
+			tokenStore = secrets.NewMemoryStore()
+			return tokenStore
+		}
+		tokenStore = secrets.Default(dir)
+	}
+	return tokenStore
+}
+
+// ReadGithubToken returns the Github API token of the account, searching the
+// token sources in the given order: the secret store, environment variables, and
+// the gh CLI's hosts file. Returns an empty token if none is found.
+func ReadGithubToken(host github.GithubConfig, sources []string) (tokensource.Token, error) {
+	for _, src := range sources {
+		var tok tokensource.Token
+		found := false
+		switch src {
+		case tokensource.Store:
+			token, err := readStoredToken(host.Name)
+			if err != nil {
+				return tokensource.Token{}, err
+			}
+			tok, found = tokensource.Token{Value: token, Source: src, Detail: secretStore().Name()}, token != ""
+		case tokensource.Env:
+			tok, found = tokensource.FromEnv(host.IsEnterprise())
+		case tokensource.GhCLI:
+			var err error
+			tok, found, err = tokensource.FromGhCLI(ghHostsFile, webHostname(host))
+			if err != nil {
+				logger.Error("read gh CLI token failed", err)
+			}
+		default:
+			logger.Warn("unknown token source %s", src)
+		}
+		if found {
+			return tok, nil
+		}
+	}
+	logger.Info("load github settings: no token found for account %s.", host.Name)
+	return tokensource.Token{}, nil
+}
+
+// The gh CLI's hosts file, searched for tokens
+var ghHostsFile = tokensource.GhHostsFile()
+
+// webHostname returns the hostname of the Github web host, eg: github.com
+func webHostname(host github.GithubConfig) string {
+	u, err := url.Parse(host.WebURL())
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// readStoredToken reads the Github API token of the account from the secret store.
+// A token stored by earlier versions, in the secret store without an account or in
+// the legacy plain text file, is moved to the account.
+func readStoredToken(account string) (string, error) {
+	token, err := secretStore().Get(tokenKey(account))
+	if err == nil {
+		return token, nil
+	}
+	if !errors.Is(err, secrets.ErrNotFound) {
+		return "", fmt.Errorf("read token from %s failed: %w", secretStore().Name(), err)
+	}
+	token, err = secretStore().Get(githubTokenKey)
+	if err == nil {
+		return migrateUnnamedToken(account, token)
+	}
+	if !errors.Is(err, secrets.ErrNotFound) {
+		return "", fmt.Errorf("read token from %s failed: %w", secretStore().Name(), err)
+	}
+	if GITHUB_CONFIG_FILE == "" || !fileExists(GITHUB_CONFIG_FILE) {
 		return "", nil
 	}
+	return migrateLegacyToken(account)
+}
+
+// migrateUnnamedToken moves a token stored without an account to the account
+func migrateUnnamedToken(account string, token string) (string, error) {
+	if err := secretStore().Set(tokenKey(account), token); err != nil {
+		return "", fmt.Errorf("migrate token to account %s failed: %w", account, err)
+	}
+	if err := secretStore().Delete(githubTokenKey); err != nil {
+		return "", fmt.Errorf("remove unnamed token failed: %w", err)
+	}
+	logger.Info("migrated Github token to account %s", account)
+	return token, nil
+}
+
+// migrateLegacyToken moves the token from the legacy plain text file to the
+// account in the secret store, and removes the file
+func migrateLegacyToken(account string) (string, error) {
 	token, err := readToken()
 	if err != nil {
 		return "", err
 	}
+	token = strings.TrimSpace(token)
+	if token != "" {
+		if err := secretStore().Set(tokenKey(account), token); err != nil {
+			return "", fmt.Errorf("migrate token to %s failed: %w", secretStore().Name(), err)
+		}
+	}
+	if err := os.Remove(GITHUB_CONFIG_FILE); err != nil {
+		return "", fmt.Errorf("remove legacy token file failed: %w", err)
+	}
+	logger.Info("migrated Github token from %s to %s", GITHUB_CONFIG_FILE, secretStore().Name())
 	return token, nil
 }
 
-// Regex to validate the token is alphanumeric
-var rgx = regexp.MustCompile("^[A-Za-z0-9]*$")
+// storedToken returns the token source of tokens saved in the secret store
+func storedToken() tokensource.Token {
+	return tokensource.Token{Source: tokensource.Store, Detail: secretStore().Name()}
+}
+
+// Regex to validate the token characters. OAuth and fine-grained tokens contain underscores, eg: gho_xxx
+var rgx = regexp.MustCompile("^[A-Za-z0-9_]*$")
 
-// saveToken saves the Github token to a local file
-func saveToken(token string) error {
+// saveToken saves the Github token of the account to the secret store
+func saveToken(account string, token string) error {
 	if token == "" {
 		return fmt.Errorf("token is empty")
 	}
 	if !rgx.MatchString(token) {
-		return fmt.Errorf("token must be alphanumeric characters")
+		return fmt.Errorf("token must be alphanumeric or underscore characters")
 	}
-	// Ensure the user config dir exists
-	configDir := userConfigPath()
-	if !dirExists(configDir) {
-		err := os.Mkdir(configDir, 0755)
-		if err != nil {
-			return fmt.Errorf("create user config dir failed: %w", err)
-		}
-		logger.Info("created user config directory at %s", configDir)
-	}
-	// Save the token to file
-	data := []byte(token)
-	err := os.WriteFile(GITHUB_CONFIG_FILE, data, 0644)
-	if err != nil {
-		return fmt.Errorf("save token to file failed: %w", err)
+	if err := secretStore().Set(tokenKey(account), token); err != nil {
+		return fmt.Errorf("save token to %s failed: %w", secretStore().Name(), err)
 	}
-	logger.Debug("saved Github token: %s", GITHUB_CONFIG_FILE)
+	logger.Debug("saved Github token for account %s to %s", account, secretStore().Name())
 	return nil
 }
 
-// readToken reads the Github token from a local file
+// readToken reads the Github token from the legacy plain text file
 // returns error on empty
 func readToken() (string, error) {
 	if !fileExists(GITHUB_CONFIG_FILE) {
@@ -166,17 +547,19 @@ func fileExists(fp string) bool {
 	return err == nil && !data.IsDir()
 }
 
-// dirExists checks if a directory exists at the given path
-func dirExists(fp string) bool {
-	data, err := os.Stat(fp)
-	return err == nil && data.IsDir()
+// configDir returns the app config directory
+func configDir() (string, error) {
+	if ConfigDir != "" {
+		return ConfigDir, nil
+	}
+	return config.Dir()
 }
 
-// userConfigPath returns the save path for config settings
-func userConfigPath() string {
-	user, err := user.Current()
-	if err != nil {
-		log.Fatalf(err.Error())
+// legacyFile returns the path of a file in the legacy config directory,
+// or an empty string if the directory is unknown
+func legacyFile(name string) string {
+	if legacyConfigDir == "" {
+		return ""
 	}
-	return path.Join(user.HomeDir, CONFIG_DIR_NAME)
+	return path.Join(legacyConfigDir, name)
 }
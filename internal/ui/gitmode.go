@@ -0,0 +1,178 @@
+// Git mode: editing a gist in a local git clone, to support binary files
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/fieldse/gist-editor/internal/gitgist"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// gitBackend returns the git backend for the active account's Github host and token.
+// Gists are cloned into the account's directory under clones in the config dir.
+func (cfg *AppConfig) gitBackend() (*gitgist.Backend, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.GithubConfig.Name
+	if err := github.ValidAccountName(name); err != nil {
+		return nil, err
+	}
+	b := gitgist.Backend{}.New(*cfg.GithubConfig, filepath.Join(dir, "clones", name))
+	b.Author = cfg.githubLogin()
+	return b, nil
+}
+
+// CloneGist switches the open gist to git mode: the gist is cloned in the background, and
+// saves are committed and pushed. Unsaved changes are kept.
+func (cfg *AppConfig) CloneGist() {
+	w := cfg.Editor.editWindow
+	current := cfg.currentGistContent()
+	if current == nil || current.IsNew() {
+		return
+	}
+	if err := current.CheckComplete(); err != nil {
+		dialog.ShowError(fmt.Errorf("load the full gist before cloning: %w", err), w)
+		return
+	}
+	b, err := cfg.gitBackend()
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	f, ed, id := cfg.CurrentFile, cfg.Editor, current.ID
+	ed.actions.cloneButton.Disable()
+	go func() {
+		repo, err := b.Clone(id)
+		if err != nil {
+			logger.Error("clone gist failed", err)
+			dialog.ShowError(fmt.Errorf("cloning gist failed: %w", err), w)
+			ed.actions.cloneButton.Enable()
+			return
+		}
+		if cfg.EditWindow.tabWith(f) == nil {
+			return // closed while cloning
+		}
+		f.repo = repo
+		ed.actions.SetGitMode(true)
+		ed.metadata.SetVisible(false) // the description and visibility are not stored in git
+		logger.Info("editing gist %s in git mode at %s", id, repo.Dir)
+	}()
+}
+
+// PullGist pulls the latest changes to the gist in git mode, and reloads the editor,
+// after asking what to do with any unsaved changes.
+func (cfg *AppConfig) PullGist() {
+	if cfg.CurrentFile.repo == nil {
+		return
+	}
+	cfg.confirmDiscard(cfg.pullGist)
+}
+
+// pullGist pulls the latest changes to the gist in git mode in the background, and reloads the editor
+func (cfg *AppConfig) pullGist() {
+	w := cfg.Editor.editWindow
+	f, ed := cfg.CurrentFile, cfg.Editor
+	if f.repo == nil {
+		return
+	}
+	go func() {
+		if err := f.repo.Pull(); err != nil {
+			logger.Error("pull gist failed", err)
+			dialog.ShowError(fmt.Errorf("pulling gist failed: %w", err), w)
+			return
+		}
+		if cfg.EditWindow.tabWith(f) == nil {
+			return // closed while pulling
+		}
+		cfg.reloadFromRepo(f, ed)
+	}()
+}
+
+// AttachFile asks for a local file, which may be binary, and adds it to the gist in git mode.
+// The file is committed and pushed immediately, in the background.
+func (cfg *AppConfig) AttachFile() {
+	w := cfg.Editor.editWindow
+	repo := cfg.CurrentFile.repo
+	if repo == nil {
+		return
+	}
+	dialog.ShowFileOpen(func(read fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if read == nil {
+			return // cancelled
+		}
+		read.Close()
+		go func() {
+			name, err := repo.AddFile(read.URI().Path())
+			if err == nil {
+				err = repo.Push()
+			}
+			if err != nil {
+				logger.Error("attach file failed", err)
+				dialog.ShowError(fmt.Errorf("adding file failed: %w", err), w)
+				return
+			}
+			dialog.ShowInformation("File added", name+" was added to the gist", w)
+		}()
+	}, w)
+}
+
+// saveGitGist commits the gist's files to the document's clone and pushes them in the background,
+// then updates its editor. done is called with the result once the gist is pushed.
+func (cfg *AppConfig) saveGitGist(f *GistFile, g github.Gist, done func(err error)) {
+	repo := f.repo
+	f.saving = true
+	go func() {
+		err := saveToRepo(repo, g)
+		f.saving = false
+		if err != nil {
+			done(err)
+			return
+		}
+		saved := g
+		if loaded, err := repo.Gist(); err != nil {
+			logger.Error("read gist clone failed", err)
+		} else {
+			saved.Files = loaded.Files
+		}
+		cfg.gistSaved(f, g, saved)
+		logger.Info("saved gist %s with git", g.ID)
+		done(nil)
+	}()
+}
+
+// saveToRepo commits the gist's files to its clone, and pushes them
+func saveToRepo(repo *gitgist.Repo, g github.Gist) error {
+	if _, err := repo.Save(g, "Update "+g.Title()); err != nil {
+		logger.Error("commit gist failed", err)
+		return fmt.Errorf("saving gist failed: %w", err)
+	}
+	if err := repo.Push(); err != nil {
+		logger.Error("push gist failed", err)
+		return fmt.Errorf("the changes were committed locally, but pushing failed: %w", err)
+	}
+	return nil
+}
+
+// reloadFromRepo reloads the files of a document's gist from its clone, keeping the other gist details
+func (cfg *AppConfig) reloadFromRepo(f *GistFile, ed *Editor) {
+	loaded, err := f.repo.Gist()
+	if err != nil {
+		logger.Error("read gist clone failed", err)
+		dialog.ShowError(fmt.Errorf("reading gist clone failed: %w", err), ed.editWindow)
+		return
+	}
+	g := *f.Gist
+	g.Files = loaded.Files
+	f.Gist = &g
+	ed.ReloadGist(f.Gist)
+}
@@ -0,0 +1,277 @@
+// Gist revision history window
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/diff"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// HistoryWindow lists the revisions of the open gist, and allows viewing,
+// comparing and restoring older revisions
+type HistoryWindow struct {
+	window    fyne.Window
+	list      *widget.List
+	viewer    *widget.TextGrid // read-only view of a revision or diff
+	viewTitle *widget.Label
+	mu        sync.Mutex // guards the fields below, which are also set by background loads
+	revisions []github.Revision
+	selected  int // index of the selected revision, or -1
+	gistID    string
+	loaded    map[string]github.Gist // the gist at each fetched revision, by version
+}
+
+// newHistoryWindow creates a HistoryWindow instance with its window
+func newHistoryWindow(cfg *AppConfig) *HistoryWindow {
+	a := *cfg.App
+	w := a.NewWindow("Gist History")
+	w.Resize(fyne.NewSize(900, 600))
+	w.SetCloseIntercept(w.Hide) // keep the window for reuse
+
+	hw := &HistoryWindow{
+		window:    w,
+		viewer:    widget.NewTextGrid(),
+		viewTitle: widget.NewLabel("Select a revision"),
+		selected:  -1,
+		loaded:    map[string]github.Gist{},
+	}
+	hw.list = widget.NewList(
+		func() int {
+			hw.mu.Lock()
+			defer hw.mu.Unlock()
+			return len(hw.revisions)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			hw.mu.Lock()
+			if i >= len(hw.revisions) {
+				hw.mu.Unlock()
+				return
+			}
+			r := hw.revisions[i]
+			hw.mu.Unlock()
+			o.(*widget.Label).SetText(revisionLabel(r))
+		})
+	hw.list.OnSelected = func(i widget.ListItemID) {
+		hw.mu.Lock()
+		hw.selected = i
+		hw.mu.Unlock()
+		hw.openRevision(cfg)
+	}
+
+	// Buttons
+	spacer := layout.NewSpacer()
+	openButton := widget.NewButton("Open", func() { hw.openRevision(cfg) })
+	diffButton := widget.NewButton("Diff with current", func() { hw.diffRevision(cfg) })
+	restoreButton := widget.NewButton("Restore", func() { hw.restoreRevision(cfg) })
+	closeButton := widget.NewButton("Close", w.Hide)
+	buttons := ButtonContainer(5, spacer, openButton, diffButton, restoreButton, closeButton)
+
+	viewPane := container.NewBorder(hw.viewTitle, nil, nil, nil, container.NewScroll(hw.viewer))
+	split := container.NewHSplit(hw.list, viewPane)
+	split.SetOffset(0.35)
+
+	content := container.NewBorder(TitleBox("Revisions"), buttons, nil, nil, split)
+	w.SetContent(content)
+	w.CenterOnScreen()
+	return hw
+}
+
+// Show shows the history window, and loads the revisions of the given gist
+func (h *HistoryWindow) Show(cfg *AppConfig, gistID string) {
+	h.mu.Lock()
+	if gistID != h.gistID {
+		h.loaded = map[string]github.Gist{}
+	}
+	h.gistID = gistID
+	h.revisions = nil
+	h.selected = -1
+	h.mu.Unlock()
+	h.list.UnselectAll()
+	h.list.Refresh()
+	h.setView("Loading revisions...", "")
+	h.window.Show()
+
+	go func() {
+		revisions, err := cfg.GithubClient().ListRevisions(gistID)
+		if err != nil {
+			logger.Error("list revisions failed", err)
+			dialog.ShowError(fmt.Errorf("loading history failed: %w", err), h.window)
+			return
+		}
+		h.mu.Lock()
+		if h.gistID != gistID {
+			h.mu.Unlock()
+			return // the history of another gist was shown while loading
+		}
+		h.revisions = revisions
+		h.mu.Unlock()
+		h.list.Refresh()
+		h.setView("Select a revision", "")
+	}()
+}
+
+// Hide hides the history window
+func (h *HistoryWindow) Hide() {
+	h.window.Hide()
+}
+
+// setView sets the title and text of the read-only viewer
+func (h *HistoryWindow) setView(title string, text string) {
+	h.viewTitle.SetText(title)
+	h.viewer.SetText(text)
+}
+
+// selectedRevision returns the selected revision and the ID of its gist.
+// Returns false if no revision is selected.
+func (h *HistoryWindow) selectedRevision() (github.Revision, string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.selected < 0 || h.selected >= len(h.revisions) {
+		return github.Revision{}, "", false
+	}
+	return h.revisions[h.selected], h.gistID, true
+}
+
+// withSelectedRevision calls fn with the gist ID, the selected revision and the gist at that revision.
+// Revisions are fetched in the background, once each, and only shown if still selected.
+func (h *HistoryWindow) withSelectedRevision(cfg *AppConfig, fn func(gistID string, r github.Revision, g github.Gist)) {
+	r, gistID, ok := h.selectedRevision()
+	if !ok {
+		dialog.ShowError(fmt.Errorf("no revision selected"), h.window)
+		return
+	}
+	h.mu.Lock()
+	g, ok := h.loaded[r.Version]
+	h.mu.Unlock()
+	if ok {
+		fn(gistID, r, g)
+		return
+	}
+	h.setView(fmt.Sprintf("Loading revision %s...", r.ShortVersion()), "")
+	go func() {
+		g, err := cfg.GithubClient().GetRevision(gistID, r.Version)
+		if err != nil {
+			logger.Error("load revision failed", err)
+			dialog.ShowError(fmt.Errorf("loading revision %s failed: %w", r.ShortVersion(), err), h.window)
+			return
+		}
+		h.mu.Lock()
+		if h.gistID == gistID {
+			h.loaded[r.Version] = g
+		}
+		h.mu.Unlock()
+		if selected, id, ok := h.selectedRevision(); !ok || id != gistID || selected.Version != r.Version {
+			return // another revision was selected while loading
+		}
+		fn(gistID, r, g)
+	}()
+}
+
+// openRevision shows the content of the selected revision, read-only
+func (h *HistoryWindow) openRevision(cfg *AppConfig) {
+	h.withSelectedRevision(cfg, func(_ string, r github.Revision, g github.Gist) {
+		h.setView(fmt.Sprintf("Revision %s (read-only)", r.ShortVersion()), gistText(g))
+	})
+}
+
+// diffRevision shows the changes between the selected revision and the content in the editor
+func (h *HistoryWindow) diffRevision(cfg *AppConfig) {
+	h.withSelectedRevision(cfg, func(gistID string, r github.Revision, g github.Gist) {
+		current := cfg.currentGistContent()
+		if current == nil || current.ID != gistID {
+			dialog.ShowInformation("Diff", "This gist is no longer open in the editor.", h.window)
+			return
+		}
+		text := gistDiff(g, *current, r.ShortVersion())
+		if text == "" {
+			text = "No differences."
+		}
+		h.setView(fmt.Sprintf("Changes from revision %s to current", r.ShortVersion()), text)
+	})
+}
+
+// restoreRevision saves the selected revision as a new revision of the gist
+func (h *HistoryWindow) restoreRevision(cfg *AppConfig) {
+	h.withSelectedRevision(cfg, func(gistID string, r github.Revision, g github.Gist) {
+		current := cfg.currentGistContent()
+		if current == nil || current.ID != gistID {
+			dialog.ShowInformation("Restore", "Open this gist in the editor to restore a revision.", h.window)
+			return
+		}
+		msg := fmt.Sprintf("Restore revision %s as a new revision?\nAny unsaved changes in the editor will be replaced.", r.ShortVersion())
+		dialog.ShowConfirm("Restore revision", msg, func(ok bool) {
+			if !ok {
+				return
+			}
+			cfg.RestoreRevision(g, func(err error) {
+				if err != nil {
+					dialog.ShowError(err, h.window)
+					return
+				}
+				h.Show(cfg, gistID) // reload, to show the new revision
+			})
+		}, h.window)
+	})
+}
+
+// revisionLabel returns the list label for a revision
+func revisionLabel(r github.Revision) string {
+	author := r.Author
+	if author == "" {
+		author = "unknown"
+	}
+	return fmt.Sprintf("%s  %s  by %s  (+%d -%d)",
+		r.ShortVersion(), r.CommittedAt.Local().Format("2006-01-02 15:04"), author, r.Additions, r.Deletions)
+}
+
+// gistText returns the contents of all files in a gist, each with a filename header
+func gistText(g github.Gist) string {
+	var parts []string
+	for _, f := range g.Files {
+		parts = append(parts, fmt.Sprintf("==> %s <==\n%s", f.Filename, f.Content))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// gistDiff returns a unified diff of all files from gist a to gist b.
+// label identifies gist a in the file headers.
+func gistDiff(a github.Gist, b github.Gist, label string) string {
+	names := map[string]bool{}
+	for _, name := range append(a.Filenames(), b.Filenames()...) {
+		names[name] = true
+	}
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var parts []string
+	for _, name := range sorted {
+		var contentA, contentB string
+		if f := a.File(name); f != nil {
+			contentA = f.Content
+		}
+		if f := b.File(name); f != nil {
+			contentB = f.Content
+		}
+		d := diff.Unified(contentA, contentB, name+" @ "+label, name+" (current)")
+		if d != "" {
+			parts = append(parts, d)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
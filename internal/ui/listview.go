@@ -1,18 +1,27 @@
 package ui
 
 import (
+	"fmt"
+	"time"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/icons"
 )
 
-// ListView is the user's Gist list view window
+// ListView is the user's Gist list view window, with sections for the user's
+// own gists, their starred gists, and browsing other users' gists
 type ListView struct {
-	window fyne.Window
-	list   *widget.List
-	gists  []github.Gist
+	window    fyne.Window
+	tabs      *container.AppTabs
+	mine      *gistSection  // the user's own gists
+	starred   *gistSection  // the user's starred gists
+	browse    *BrowseView   // other users' gists
+	rateLabel *widget.Label // the remaining API rate limit quota
 }
 
 // Show shows the list view window
@@ -27,46 +36,113 @@ func (l *ListView) Hide() {
 
 // SetGists populates the list view data
 func (l *ListView) SetGists(data []github.Gist) {
-	l.gists = data
-	l.list = newList(data)
+	l.mine.Set(data)
 }
 
-// Clear clears the list view data
-func (l *ListView) Clear() {
-	l.gists = []github.Gist{}
-	l.list = newList(l.gists)
+// SetStarred populates the Starred section data
+func (l *ListView) SetStarred(data []github.Gist) {
+	l.starred.Set(data)
 }
 
-// newList returns a new Fyne list widget with the given Gist data
-func newList(gists []github.Gist) *widget.List {
-	var data []string
-	for _, x := range gists {
-		data = append(data, x.Filename)
+// Refresh loads the active section from Github. The first load fetches the first
+// page; later refreshes only fetch gists updated since the last load.
+func (l *ListView) Refresh() {
+	l.activeSection().Refresh()
+}
+
+// Reload loads the active section from Github again in full, removing gists that are
+// no longer listed, such as deleted or unstarred gists
+func (l *ListView) Reload() {
+	l.activeSection().Reload()
+}
+
+// activeSection returns the section of the active tab
+func (l *ListView) activeSection() *gistSection {
+	switch l.tabs.SelectedIndex() {
+	case 1:
+		return l.starred
+	case 2:
+		return l.browse.section
 	}
+	return l.mine
+}
+
+// SetRateLimit shows the remaining Github API quota
+func (l *ListView) SetRateLimit(r github.RateLimit) {
+	l.rateLabel.SetText(rateLimitText(r))
+}
+
+// rateLimitText returns the display text for the Github API quota
+func rateLimitText(r github.RateLimit) string {
+	if !r.IsKnown() {
+		return ""
+	}
+	return fmt.Sprintf("API requests remaining: %d/%d, resets at %s", r.Remaining, r.Limit, r.Reset.Local().Format("15:04"))
+}
+
+// SetAccount shows the name of the active account in the window title
+func (l *ListView) SetAccount(name string) {
+	l.window.SetTitle("Your Gists (" + name + ")")
+}
+
+// Clear clears the list view data, so that it is reloaded on the next refresh
+func (l *ListView) Clear() {
+	l.mine.Reset()
+	l.starred.Reset()
+	l.browse.Reset()
+}
+
+// newList returns a new Fyne list widget, displaying the Gist data returned by getData.
+// onSelect is called with the selected Gist. If showOwner is true, the owner of each gist is shown.
+func newList(getData func() []github.Gist, onSelect func(github.Gist), showOwner bool) *widget.List {
 	l := widget.NewList(
 		func() int {
-			return len(data)
+			return len(getData())
 		},
 		func() fyne.CanvasObject {
-			return widget.NewLabel("template")
+			badge := widget.NewLabel("")
+			return container.NewBorder(nil, nil, nil, badge, widget.NewLabel("template"))
 		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
-			o.(*widget.Label).SetText(data[i])
+			g := getData()[i]
+			item := o.(*fyne.Container)
+			item.Objects[0].(*widget.Label).SetText(listLabel(g, showOwner))
+			badge := item.Objects[1].(*widget.Label)
+			badge.Importance = visibilityImportance(g.Public)
+			badge.SetText(visibilityLabel(g.Public))
 		})
+	l.OnSelected = func(i widget.ListItemID) {
+		onSelect(getData()[i])
+	}
 	return l
 }
 
-// Returns a list view widget of all user gists
-func listWidget(hide func(), gists []github.Gist) *fyne.Container {
-	spacer := layout.NewSpacer()
+// listLabel returns the list label for a gist, optionally including its owner
+func listLabel(g github.Gist, showOwner bool) string {
+	if showOwner && g.AuthorId != "" {
+		return g.Title() + "  (" + g.AuthorId + ")"
+	}
+	return g.Title()
+}
+
+// visibilityImportance returns the style of the visibility badge, highlighting public gists
+func visibilityImportance(public bool) widget.Importance {
+	if public {
+		return widget.WarningImportance
+	}
+	return widget.LowImportance
+}
+
+// Returns a list view widget with tabs for the user's gists and starred gists
+func listWidget(hide func(), refresh func(), tabs *container.AppTabs, rateLabel *widget.Label) *fyne.Container {
 	okButton := widget.NewButton("Ok", hide)
+	refreshButton := widget.NewButton("Refresh", refresh)
 
 	// List data view
-	l := newList(gists)
 	titleContainer := TitleBox("Your Gists")
-	listContainer := container.NewStack(l)
+	listContainer := container.NewStack(tabs)
 
-	buttons := ButtonContainer(3, spacer, spacer, okButton)
+	buttons := container.NewBorder(nil, nil, rateLabel, ButtonContainer(2, refreshButton, okButton))
 
 	// Total content includes title, list section, and buttons
 	content := container.NewBorder(titleContainer, buttons, nil, nil, listContainer)
@@ -79,11 +155,46 @@ func (l ListView) New(cfg *AppConfig) *ListView {
 	w := a.NewWindow("Your Gists")
 	w.Resize(fyne.NewSize(800, 600))
 
-	content := listWidget(w.Hide, []github.Gist{})
+	lv := &ListView{
+		window:    w,
+		rateLabel: widget.NewLabel(""),
+	}
+	openGist := func(g github.Gist) {
+		cfg.OpenGist(g.ID)
+	}
+	onError := func(err error) {
+		dialog.ShowError(fmt.Errorf("loading gists failed: %w", err), w)
+	}
+	lv.mine = newGistSection(
+		func(o github.ListOptions) (github.GistPage, error) { return cfg.GithubClient().ListGists(o) },
+		func(since time.Time) ([]github.Gist, error) { return cfg.GithubClient().ListUpdatedGists(since) },
+		openGist, false, onError)
+	lv.starred = newGistSection(
+		func(o github.ListOptions) (github.GistPage, error) { return cfg.GithubClient().ListStarred(o) },
+		func(since time.Time) ([]github.Gist, error) { return cfg.GithubClient().ListUpdatedStarred(since) },
+		openGist, true, onError)
+	lv.browse = newBrowseView(cfg, w)
+	lv.tabs = container.NewAppTabs(
+		container.NewTabItemWithIcon("Your Gists", icons.ToolbarIcons.FolderIcon, lv.mine.list),
+		container.NewTabItemWithIcon("Starred", icons.ToolbarIcons.FolderStarIcon, lv.starred.list),
+		container.NewTabItemWithIcon("Browse", theme.SearchIcon(), lv.browse.Content),
+	)
+	lv.tabs.OnSelected = func(*container.TabItem) {
+		cfg.RefreshGists()
+	}
+	content := listWidget(w.Hide, cfg.ReloadGists, lv.tabs, lv.rateLabel)
 	w.SetContent(content)
 	w.CenterOnScreen()
 
-	return &ListView{
-		window: w,
-	}
+	return lv
+}
+
+// StarredSelected returns true if the Starred section is the active tab
+func (l *ListView) StarredSelected() bool {
+	return l.tabs.SelectedIndex() == 1
+}
+
+// BrowseSelected returns true if the Browse section is the active tab
+func (l *ListView) BrowseSelected() bool {
+	return l.tabs.SelectedIndex() == 2
 }
@@ -5,16 +5,22 @@ import (
 	"fyne.io/fyne/v2"
 )
 
-// Returns a main File menu, and a function to toggle Save allowed
-func FileMenu(cfg *AppConfig) (*fyne.MainMenu, func(bool)) {
+// Returns a main File menu, a function to toggle Save allowed, a function
+// to update the account switcher, and a function to update the recent documents
+func FileMenu(cfg *AppConfig) (*fyne.MainMenu, func(bool), func(), func()) {
 	// File menu
 	openMenu := fyne.NewMenuItem("Open...", cfg.OpenFile)
+	recentMenu := fyne.NewMenuItem("Open Recent", nil)
+	recentMenu.ChildMenu = fyne.NewMenu("Open Recent")
 	saveMenu := fyne.NewMenuItem("Save", cfg.SaveFile)
 	saveAsMenu := fyne.NewMenuItem("Save as...", cfg.SaveFileAs)
 	saveMenu.Disabled = true   // save menus disabled until we have an open file
 	saveAsMenu.Disabled = true // save menus disabled until we have an open file
 	closeMenu := fyne.NewMenuItem("Close", cfg.CloseFile)
-	fileMenu := fyne.NewMenu("File", openMenu, saveMenu, saveAsMenu, closeMenu)
+	preferencesMenu := fyne.NewMenuItem("Preferences...", cfg.ShowPreferences)
+	quitMenu := fyne.NewMenuItem("Quit", cfg.Exit)
+	quitMenu.IsQuit = true // replaces Fyne's default Quit item, which would not check for unsaved changes
+	fileMenu := fyne.NewMenu("File", openMenu, recentMenu, saveMenu, saveAsMenu, closeMenu, fyne.NewMenuItemSeparator(), preferencesMenu, quitMenu)
 
 	// TODO - Keyboard Shortcuts
 
@@ -25,10 +31,45 @@ func FileMenu(cfg *AppConfig) (*fyne.MainMenu, func(bool)) {
 	}
 
 	// Github settings & authentication settings
-	githubTokenMenu := fyne.NewMenuItem("Github API Token", cfg.ShowGithubTokenModal)
-	githubMenu := fyne.NewMenu("Github", githubTokenMenu)
+	githubTokenMenu := fyne.NewMenuItem("Github Settings", cfg.ShowGithubTokenModal)
+	accountsMenu := fyne.NewMenuItem("Accounts", nil)
+	accountsMenu.ChildMenu = fyne.NewMenu("Accounts")
+	githubMenu := fyne.NewMenu("Github", githubTokenMenu, accountsMenu)
 
 	// Main app menu
 	mainMenu := fyne.NewMainMenu(fileMenu, githubMenu)
-	return mainMenu, setCanSave
+
+	// Function to list the accounts, with the active account checked
+	refreshAccounts := func() {
+		var items []*fyne.MenuItem
+		for _, name := range cfg.Settings.GithubAccounts().Names() {
+			name := name
+			item := fyne.NewMenuItem(name, func() { cfg.SwitchAccount(name) })
+			item.Checked = name == cfg.Settings.ActiveAccount
+			items = append(items, item)
+		}
+		items = append(items,
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Add account...", cfg.AddAccount),
+			fyne.NewMenuItem("Remove account", cfg.RemoveAccount),
+		)
+		accountsMenu.ChildMenu.Items = items
+		mainMenu.Refresh()
+	}
+	refreshAccounts()
+
+	// Function to list the recently used documents, most recent first
+	refreshRecent := func() {
+		var items []*fyne.MenuItem
+		for _, d := range cfg.sessionState().Recent {
+			d := d
+			items = append(items, fyne.NewMenuItem(recentLabel(d), func() { cfg.OpenRecent(d) }))
+		}
+		clearMenu := fyne.NewMenuItem("Clear recent", cfg.ClearRecent)
+		clearMenu.Disabled = len(items) == 0
+		items = append(items, fyne.NewMenuItemSeparator(), clearMenu)
+		recentMenu.ChildMenu.Items = items
+		mainMenu.Refresh()
+	}
+	return mainMenu, setCanSave, refreshAccounts, refreshRecent
 }
@@ -0,0 +1,79 @@
+// Gist metadata bar for the editor: description and visibility
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Visibility options for the metadata bar
+const (
+	visibilitySecret = "Secret"
+	visibilityPublic = "Public"
+)
+
+// MetadataBar edits the description and visibility of the gist in the editor.
+// Github only allows the visibility to be set when a gist is created.
+type MetadataBar struct {
+	Content     *fyne.Container
+	description *widget.Entry
+	visibility  *widget.Select
+	editor      *Editor
+}
+
+// New returns a new MetadataBar for the given editor
+func (m MetadataBar) New(e *Editor) *MetadataBar {
+	mb := &MetadataBar{editor: e}
+	mb.description = widget.NewEntry()
+	mb.description.PlaceHolder = "Gist description..."
+	mb.description.OnChanged = func(s string) {
+		if e.gist != nil && e.gist.Description != s {
+			e.gist.Description = s
+			e.changed()
+		}
+	}
+	mb.visibility = widget.NewSelect([]string{visibilitySecret, visibilityPublic}, func(s string) {
+		if e.gist != nil && e.gist.Public != (s == visibilityPublic) {
+			e.gist.Public = s == visibilityPublic
+			e.changed()
+		}
+	})
+	mb.Content = container.NewBorder(nil, nil, widget.NewLabel("Description"), mb.visibility, mb.description)
+	return mb
+}
+
+// Refresh updates the fields from the editor's gist.
+// The visibility can only be changed for gists not yet created on Github.
+func (m *MetadataBar) Refresh() {
+	g := m.editor.gist
+	if g == nil {
+		m.description.SetText("")
+		m.visibility.ClearSelected()
+		return
+	}
+	m.description.SetText(g.Description)
+	m.visibility.SetSelected(visibilityLabel(g.Public))
+	if g.IsNew() {
+		m.visibility.Enable()
+	} else {
+		m.visibility.Disable()
+	}
+}
+
+// SetVisible shows or hides the metadata bar. Local files have no metadata.
+func (m *MetadataBar) SetVisible(b bool) {
+	if b {
+		m.Content.Show()
+	} else {
+		m.Content.Hide()
+	}
+}
+
+// visibilityLabel returns the display label for a gist's visibility
+func visibilityLabel(public bool) string {
+	if public {
+		return visibilityPublic
+	}
+	return visibilitySecret
+}
@@ -0,0 +1,310 @@
+// Preferences window, for editor and app behaviour
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/config"
+	"github.com/fieldse/gist-editor/internal/logger"
+)
+
+// Display labels for the wrap mode and theme preferences
+var (
+	wrapLabels  = map[string]string{config.WrapOff: "Off", config.WrapWord: "Word", config.WrapChar: "Character"}
+	themeLabels = map[string]string{config.ThemeSystem: "System", config.ThemeLight: "Light", config.ThemeDark: "Dark"}
+)
+
+// PreferencesWindow is the preferences modal
+type PreferencesWindow struct {
+	dialog      *dialog.FormDialog
+	wrap        *widget.Select
+	theme       *widget.Select
+	filename    *widget.Entry
+	content     *widget.Entry
+	visibility  *widget.Select
+	autosave    *widget.Check
+	interval    *widget.Entry
+	mainSize    *widget.Entry
+	listSize    *widget.Entry
+	editorSize  *widget.Entry
+	historySize *widget.Entry
+	session     *widget.Check
+}
+
+// New returns a new PreferencesWindow
+func (p PreferencesWindow) New(cfg *AppConfig) *PreferencesWindow {
+	pw := &PreferencesWindow{}
+	pw.dialog = preferencesUI(cfg, pw)
+	return pw
+}
+
+// Show shows the preferences modal, with the current preferences
+func (p *PreferencesWindow) Show(prefs config.Preferences) {
+	p.wrap.SetSelected(wrapLabels[prefs.WrapMode])
+	p.theme.SetSelected(themeLabels[prefs.Theme])
+	p.filename.SetText(prefs.NewGistFilename)
+	p.content.SetText(prefs.NewGistContent)
+	p.visibility.SetSelected(visibilityLabel(prefs.NewGistPublic))
+	p.autosave.SetChecked(prefs.Autosave)
+	p.interval.SetText(strconv.Itoa(prefs.AutosaveInterval))
+	p.mainSize.SetText(prefs.WindowSizes.Main.String())
+	p.listSize.SetText(prefs.WindowSizes.List.String())
+	p.editorSize.SetText(prefs.WindowSizes.Editor.String())
+	p.historySize.SetText(prefs.WindowSizes.History.String())
+	p.session.SetChecked(prefs.RestoreSession)
+	p.dialog.Show()
+}
+
+// preferences returns the preferences entered in the form
+func (p *PreferencesWindow) preferences() (config.Preferences, error) {
+	prefs := config.Preferences{
+		WrapMode:        labelKey(wrapLabels, p.wrap.Selected),
+		Theme:           labelKey(themeLabels, p.theme.Selected),
+		NewGistFilename: strings.TrimSpace(p.filename.Text),
+		NewGistContent:  p.content.Text,
+		NewGistPublic:   p.visibility.Selected == visibilityPublic,
+		Autosave:        p.autosave.Checked,
+		RestoreSession:  p.session.Checked,
+	}
+	interval, err := strconv.Atoi(strings.TrimSpace(p.interval.Text))
+	if err != nil {
+		return prefs, fmt.Errorf("autosave interval must be a number of seconds")
+	}
+	prefs.AutosaveInterval = interval
+	sizes := []struct {
+		name  string
+		field *widget.Entry
+		size  *config.Size
+	}{
+		{"main window", p.mainSize, &prefs.WindowSizes.Main},
+		{"gists window", p.listSize, &prefs.WindowSizes.List},
+		{"editor window", p.editorSize, &prefs.WindowSizes.Editor},
+		{"history window", p.historySize, &prefs.WindowSizes.History},
+	}
+	for _, s := range sizes {
+		size, err := config.ParseSize(s.field.Text)
+		if err != nil {
+			return prefs, fmt.Errorf("%s size: %w", s.name, err)
+		}
+		*s.size = size
+	}
+	return prefs, prefs.Validate()
+}
+
+// labelKey returns the key of a display label, or the label itself if not found
+func labelKey(labels map[string]string, label string) string {
+	for k, v := range labels {
+		if v == label {
+			return k
+		}
+	}
+	return label
+}
+
+// sortedLabels returns the display labels in the given key order
+func sortedLabels(labels map[string]string, keys ...string) []string {
+	var res []string
+	for _, k := range keys {
+		res = append(res, labels[k])
+	}
+	return res
+}
+
+// preferencesUI generates the preferences form, and stores its fields to the preferences window
+func preferencesUI(cfg *AppConfig, p *PreferencesWindow) *dialog.FormDialog {
+	w := cfg.MainWindow.Window
+	p.wrap = widget.NewSelect(sortedLabels(wrapLabels, config.WrapOff, config.WrapWord, config.WrapChar), nil)
+	p.theme = widget.NewSelect(sortedLabels(themeLabels, config.ThemeSystem, config.ThemeLight, config.ThemeDark), nil)
+	p.filename = widget.NewEntry()
+	p.content = widget.NewMultiLineEntry()
+	p.content.SetMinRowsVisible(3)
+	p.visibility = widget.NewSelect([]string{visibilitySecret, visibilityPublic}, nil)
+	p.autosave = widget.NewCheck("Save open documents with changes automatically", nil)
+	p.interval = widget.NewEntry()
+	p.mainSize = widget.NewEntry()
+	p.listSize = widget.NewEntry()
+	p.editorSize = widget.NewEntry()
+	p.historySize = widget.NewEntry()
+	p.session = widget.NewCheck("Reopen documents from the last session", nil)
+
+	onSave := func(ok bool) {
+		if !ok {
+			return
+		}
+		prefs, err := p.preferences()
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if err := cfg.SavePreferences(prefs); err != nil {
+			dialog.ShowError(fmt.Errorf("error saving preferences: %w", err), w)
+		}
+	}
+	items := []*widget.FormItem{
+		widget.NewFormItem("Line wrap", p.wrap),
+		widget.NewFormItem("Theme", p.theme),
+		widget.NewFormItem("New gist filename", p.filename),
+		widget.NewFormItem("New gist content", p.content),
+		widget.NewFormItem("New gist visibility", p.visibility),
+		widget.NewFormItem("Autosave", p.autosave),
+		widget.NewFormItem("Autosave after (seconds)", p.interval),
+		widget.NewFormItem("Main window size", p.mainSize),
+		widget.NewFormItem("Gists window size", p.listSize),
+		widget.NewFormItem("Editor window size", p.editorSize),
+		widget.NewFormItem("History window size", p.historySize),
+		widget.NewFormItem("On launch", p.session),
+	}
+	d := dialog.NewForm("Preferences", "Save", "Cancel", items, onSave, w)
+	d.Resize(fyne.NewSize(500, 650))
+	return d
+}
+
+// ShowPreferences shows the preferences modal
+func (cfg *AppConfig) ShowPreferences() {
+	cfg.PreferencesWindow.Show(cfg.Settings.Preferences)
+}
+
+// SavePreferences saves the preferences to the config file, and applies them to the open windows
+func (cfg *AppConfig) SavePreferences(prefs config.Preferences) error {
+	settings := *cfg.Settings
+	settings.Preferences = prefs
+	if err := saveSettings(settings); err != nil {
+		return err
+	}
+	cfg.Settings = &settings
+	cfg.applyPreferences()
+	logger.Debug("saved preferences")
+	return nil
+}
+
+// applyPreferences applies the preferences to the app and its windows
+func (cfg *AppConfig) applyPreferences() {
+	prefs := cfg.Settings.Preferences
+	(*cfg.App).Settings().SetTheme(appTheme(prefs.Theme))
+	for _, t := range cfg.EditWindow.Tabs() {
+		t.Editor.SetWrapping(wrapMode(prefs.WrapMode))
+	}
+	resize := func(w fyne.Window, s config.Size) {
+		w.Resize(fyne.NewSize(float32(s.Width), float32(s.Height)))
+	}
+	resize(cfg.MainWindow.Window, prefs.WindowSizes.Main)
+	resize(cfg.ListWindow.window, prefs.WindowSizes.List)
+	resize(cfg.EditWindow.window, prefs.WindowSizes.Editor)
+	resize(cfg.HistoryWindow.window, prefs.WindowSizes.History)
+	cfg.startAutosave(prefs)
+}
+
+// wrapMode returns the Fyne text wrapping for a wrap mode preference
+func wrapMode(mode string) fyne.TextWrap {
+	switch mode {
+	case config.WrapWord:
+		return fyne.TextWrapWord
+	case config.WrapChar:
+		return fyne.TextWrapBreak
+	}
+	return fyne.TextWrap(fyne.TextTruncateClip)
+}
+
+// appTheme returns the Fyne theme for a theme preference
+func appTheme(name string) fyne.Theme {
+	switch name {
+	case config.ThemeLight:
+		return variantTheme{Theme: theme.DefaultTheme(), variant: theme.VariantLight}
+	case config.ThemeDark:
+		return variantTheme{Theme: theme.DefaultTheme(), variant: theme.VariantDark}
+	}
+	return theme.DefaultTheme()
+}
+
+// variantTheme is a theme that always uses the given light or dark variant,
+// ignoring the system setting
+type variantTheme struct {
+	fyne.Theme
+	variant fyne.ThemeVariant
+}
+
+func (t variantTheme) Color(name fyne.ThemeColorName, _ fyne.ThemeVariant) color.Color {
+	return t.Theme.Color(name, t.variant)
+}
+
+// startAutosave sets how long the open documents may have unsaved changes before they are
+// saved automatically, if autosave is enabled
+func (cfg *AppConfig) startAutosave(prefs config.Preferences) {
+	cfg.autosaveInterval = 0
+	if prefs.Autosave {
+		cfg.autosaveInterval = time.Duration(prefs.AutosaveInterval) * time.Second
+	}
+}
+
+// autosaveDue autosaves a tab's document once it has had unsaved changes for the autosave interval.
+// It is called on edits, so that autosaving runs on the UI thread.
+func (cfg *AppConfig) autosaveDue(t *Tab) {
+	if cfg.autosaveInterval > 0 && t.File.isDirty && time.Since(t.File.dirtySince) >= cfg.autosaveInterval {
+		cfg.autosave(t)
+	}
+}
+
+// autosaveAll autosaves every open document with unsaved changes, when the user
+// leaves the app or switches tabs
+func (cfg *AppConfig) autosaveAll() {
+	if cfg.autosaveInterval == 0 {
+		return
+	}
+	for _, t := range cfg.EditWindow.Tabs() {
+		cfg.autosave(t)
+	}
+}
+
+// autosave saves a tab's document if it has unsaved changes, without making the tab active.
+// New gists and files are not saved, as choosing where to create them is left to the user.
+// Only the first of repeated failures is reported.
+func (cfg *AppConfig) autosave(t *Tab) {
+	f, ed := t.File, t.Editor
+	if !f.isOpen || !f.isDirty || f.readOnly || f.saving || cfg.fileChangedDialog != nil {
+		return
+	}
+	if (f.isLocal && f.localURI == "") || (!f.isLocal && f.Gist.IsNew()) {
+		return
+	}
+	logger.Debug("autosaving %s", ed.Title)
+	done := func(err error) {
+		if err == nil {
+			f.autosaveErr = false
+			return
+		}
+		f.dirtySince = time.Now() // retry after another interval
+		if !f.autosaveErr {
+			f.autosaveErr = true
+			dialog.ShowError(fmt.Errorf("autosaving %s failed: %w", ed.Title, err), ed.editWindow)
+		}
+	}
+	ed.SyncContent()
+	if f.isLocal {
+		cfg.checkChangedFiles() // don't replace changes made on disk
+		if cfg.fileChangedDialog != nil {
+			return
+		}
+		err := f.Save()
+		if err != nil {
+			logger.Error("autosave file failed", err)
+		} else {
+			cfg.setFileDirty(f, ed, false)
+		}
+		done(err)
+		return
+	}
+	g := gistSnapshot(f.Gist)
+	if f.repo != nil {
+		cfg.saveGitGist(f, g, done)
+		return
+	}
+	cfg.saveGist(f, g, done)
+}
@@ -0,0 +1,341 @@
+// Crash recovery: a journal of open documents with unsaved changes, offered for restore on the next launch
+package ui
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/diff"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+	"github.com/fieldse/gist-editor/internal/recovery"
+)
+
+// How often the recovery journal is written for documents with unsaved changes
+var journalInterval = 10 * time.Second
+
+// recoveryJournal returns the recovery journal, stored in the recovery directory of the config dir
+func (cfg *AppConfig) recoveryJournal() (*recovery.Journal, error) {
+	if cfg.journal == nil {
+		dir, err := configDir()
+		if err != nil {
+			return nil, err
+		}
+		cfg.journal = recovery.Journal{}.New(path.Join(dir, "recovery"))
+	}
+	return cfg.journal, nil
+}
+
+// startJournal starts writing the queued snapshots to the recovery journal periodically
+func (cfg *AppConfig) startJournal() {
+	ticker := time.NewTicker(journalInterval)
+	go func() {
+		for range ticker.C {
+			cfg.pending.Write()
+		}
+	}()
+}
+
+// journalQueue holds snapshots of documents with unsaved changes, taken where the
+// editor state lives, until they are written to the recovery journal in the background
+type journalQueue struct {
+	mu      sync.Mutex
+	journal *recovery.Journal
+	entries map[string]recovery.Entry // the latest unwritten snapshot of each document, by entry ID
+}
+
+// newJournalQueue returns an empty journalQueue
+func newJournalQueue() *journalQueue {
+	return &journalQueue{entries: map[string]recovery.Entry{}}
+}
+
+// Add queues a snapshot for the journal, replacing any unwritten snapshot of the same document
+func (q *journalQueue) Add(j *recovery.Journal, e recovery.Entry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.journal = j
+	q.entries[e.ID] = e
+}
+
+// Remove drops any unwritten snapshot of a document, and removes its entry from the journal
+func (q *journalQueue) Remove(j *recovery.Journal, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.entries, id)
+	return j.Remove(id)
+}
+
+// Write writes the queued snapshots to the journal
+func (q *journalQueue) Write() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, e := range q.entries {
+		if err := q.journal.Write(e); err != nil {
+			logger.Error("write recovery journal failed", err)
+			continue
+		}
+		delete(q.entries, id)
+		logger.Debug("wrote recovery journal for %s", e.Title)
+	}
+}
+
+// snapshotJournal queues a snapshot of a document with unsaved changes, shown in the
+// editor ed, for the recovery journal. It must be called where the editor is changed,
+// as it reads the editor's content.
+func (cfg *AppConfig) snapshotJournal(f *GistFile, ed *Editor) {
+	if !f.isOpen || !f.isDirty || f.readOnly {
+		return
+	}
+	j, err := cfg.recoveryJournal()
+	if err != nil {
+		logger.Error("open recovery journal failed", err)
+		return
+	}
+	cfg.pending.Add(j, cfg.journalEntry(f, ed))
+	f.journaled = true
+}
+
+// journalEntry returns the recovery journal entry of a document, including
+// the unsaved content of its editor
+func (cfg *AppConfig) journalEntry(f *GistFile, ed *Editor) recovery.Entry {
+	if f.journalID == "" {
+		f.journalID = recovery.NewID()
+	}
+	e := recovery.Entry{
+		ID:        f.journalID,
+		Title:     ed.Title,
+		UpdatedAt: time.Now(),
+	}
+	if f.isLocal {
+		e.Kind = recovery.KindFile
+		e.LocalURI = f.localURI
+	} else {
+		e.Kind = recovery.KindGist
+		e.GistID = f.Gist.ID
+		e.Account = cfg.GithubConfig.Name
+		e.Description = f.Gist.Description
+		e.Public = f.Gist.Public
+	}
+	for _, x := range f.Gist.Files {
+		content := x.Content
+		if x.Filename == ed.activeFile {
+			content = ed.Content()
+		}
+		e.Files = append(e.Files, recovery.File{Name: x.Filename, Content: content})
+	}
+	return e
+}
+
+// discardJournal removes the recovery journal of a document, once its
+// changes have been saved or discarded
+func (cfg *AppConfig) discardJournal(f *GistFile) {
+	if f.journalID == "" || !f.journaled {
+		return
+	}
+	f.journaled = false
+	j, err := cfg.recoveryJournal()
+	if err == nil {
+		err = cfg.pending.Remove(j, f.journalID)
+	}
+	if err != nil {
+		logger.Error("remove recovery journal failed", err)
+	}
+}
+
+// entryGist returns the gist of a recovery journal entry
+func entryGist(e recovery.Entry) github.Gist {
+	g := github.Gist{ID: e.GistID, Description: e.Description, Public: e.Public}
+	for _, f := range e.Files {
+		g.Files = append(g.Files, github.File{Filename: f.Name, Content: f.Content})
+	}
+	return g
+}
+
+// OfferRecovery lists the documents that were not cleanly closed, for example
+// after a crash, and offers to restore them
+func (cfg *AppConfig) OfferRecovery() {
+	j, err := cfg.recoveryJournal()
+	if err != nil {
+		logger.Error("open recovery journal failed", err)
+		return
+	}
+	entries, err := j.List()
+	if err != nil {
+		logger.Error("list recovery journal failed", err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+	logger.Info("found %d unsaved documents to recover", len(entries))
+	w := cfg.MainWindow.Window
+	rows := container.NewVBox()
+	var d dialog.Dialog
+	for _, e := range entries {
+		e := e
+		var row *fyne.Container
+		remove := func() {
+			rows.Remove(row)
+			if len(rows.Objects) == 0 {
+				d.Hide()
+			}
+		}
+		label := widget.NewLabel(fmt.Sprintf("%s  (%s, %s)", e.Title, entryKind(e), e.UpdatedAt.Local().Format("2006-01-02 15:04")))
+		showDiff := widget.NewButton("Show changes", func() { cfg.showRecoveryDiff(e) })
+		restore := widget.NewButton("Restore", func() {
+			if err := cfg.restoreEntry(e); err != nil {
+				logger.Error("restore document failed", err)
+				dialog.ShowError(fmt.Errorf("restoring %s failed: %w", e.Title, err), w)
+				return
+			}
+			remove()
+		})
+		restore.Importance = widget.HighImportance
+		discard := widget.NewButton("Discard", func() {
+			if err := j.Remove(e.ID); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			remove()
+		})
+		row = container.NewBorder(nil, nil, nil, container.NewHBox(showDiff, discard, restore), label)
+		rows.Add(row)
+	}
+	msg := widget.NewLabel("These documents had unsaved changes when the app last closed.")
+	d = dialog.NewCustom("Recover unsaved documents", "Close", container.NewBorder(msg, nil, nil, nil, container.NewVScroll(rows)), w)
+	d.Resize(fyne.NewSize(700, 400))
+	d.Show()
+}
+
+// entryKind returns a description of the kind of document of a journal entry
+func entryKind(e recovery.Entry) string {
+	switch {
+	case e.Kind == recovery.KindFile:
+		return "local file"
+	case e.GistID == "":
+		return "new gist"
+	}
+	return "gist"
+}
+
+// restoreEntry opens the document of a recovery journal entry with its unsaved changes, in its
+// tab if it is already open, or in a new tab. The journal entry is kept until the changes are
+// saved or discarded.
+func (cfg *AppConfig) restoreEntry(e recovery.Entry) error {
+	if e.Kind == recovery.KindGist && e.GistID != "" && e.Account != cfg.GithubConfig.Name {
+		return fmt.Errorf("the gist belongs to the account %s: switch to it to restore the gist", e.Account)
+	}
+	journaled := entryGist(e)
+	if t := cfg.EditWindow.find(isEntryDocument(e)); t != nil {
+		// Reopened from the last session: restore the changes in its tab
+		if t.File.isDirty {
+			return fmt.Errorf("%s is open with unsaved changes: save or close it to restore", t.Editor.Title)
+		}
+		f := t.File
+		if f.isLocal {
+			f.Gist = &journaled
+		} else {
+			g := f.Gist.WithFilesOf(journaled)
+			g.Description = journaled.Description
+			f.Gist = &g
+		}
+		f.journalID, f.journaled = e.ID, true
+		cfg.EditWindow.Select(t)
+	} else {
+		f := &GistFile{isOpen: true, journalID: e.ID, journaled: true}
+		switch {
+		case e.Kind == recovery.KindFile:
+			f.isLocal = true
+			f.localURI = e.LocalURI
+			f.Gist = &journaled
+			if e.LocalURI != "" {
+				data, _, _ := readURI(e.LocalURI) // the version on disk is the base of later changes by other programs
+				f.diskContent = string(data)
+			}
+		case e.GistID == "":
+			f.Gist = &journaled
+		default:
+			g, err := cfg.GithubClient().GetGist(e.GistID)
+			if err != nil {
+				return err
+			}
+			g = g.WithFilesOf(journaled)
+			g.Description = journaled.Description
+			f.Gist = &g
+		}
+		cfg.openTab(f)
+	}
+	f := cfg.CurrentFile
+	cfg.Editor.SetGist(f.Gist, !f.isLocal)
+	cfg.Editor.SetTitle(e.Title)
+	cfg.setDirty(true)
+	cfg.watchFiles()
+	cfg.MainWindow.SetCanSave(true)
+	cfg.ShowEditWindow()
+	logger.Info("restored unsaved document %s", e.Title)
+	return nil
+}
+
+// isEntryDocument returns a match for the tab of the saved document of a recovery journal entry
+func isEntryDocument(e recovery.Entry) func(f *GistFile) bool {
+	if e.Kind == recovery.KindFile {
+		return func(f *GistFile) bool { return f.isLocal && e.LocalURI != "" && f.localURI == e.LocalURI }
+	}
+	return func(f *GistFile) bool { return !f.isLocal && e.GistID != "" && f.Gist.ID == e.GistID }
+}
+
+// recoveryDiff returns the changes in a recovery journal entry, compared to the
+// file on disk, or the gist on Github. New gists are shown in full.
+func (cfg *AppConfig) recoveryDiff(e recovery.Entry) (string, error) {
+	journaled := entryGist(e)
+	switch {
+	case e.Kind == recovery.KindFile:
+		var saved []byte
+		if e.LocalURI != "" {
+			var err error
+			if saved, _, err = readURI(e.LocalURI); err != nil {
+				return "", err
+			}
+		}
+		content := ""
+		if len(e.Files) > 0 {
+			content = e.Files[0].Content
+		}
+		return diff.Unified(string(saved), content, e.Title+" (on disk)", e.Title+" (unsaved)"), nil
+	case e.GistID == "":
+		return gistText(journaled), nil
+	}
+	if e.Account != cfg.GithubConfig.Name {
+		return "", fmt.Errorf("the gist belongs to the account %s: switch to it to compare with Github", e.Account)
+	}
+	g, err := cfg.GithubClient().GetGist(e.GistID)
+	if err != nil {
+		return "", err
+	}
+	return gistDiff(g, journaled, "Github"), nil
+}
+
+// showRecoveryDiff shows the changes in a recovery journal entry
+func (cfg *AppConfig) showRecoveryDiff(e recovery.Entry) {
+	w := cfg.MainWindow.Window
+	text, err := cfg.recoveryDiff(e)
+	if err != nil {
+		logger.Error("compare recovered document failed", err)
+		dialog.ShowError(fmt.Errorf("comparing %s failed: %w", e.Title, err), w)
+		return
+	}
+	if text == "" {
+		text = "No differences."
+	}
+	viewer := widget.NewTextGrid()
+	viewer.SetText(text)
+	d := dialog.NewCustom("Unsaved changes to "+e.Title, "Close", container.NewScroll(viewer), w)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
@@ -0,0 +1,223 @@
+// Recently used documents, and reopening the documents of the last session
+package ui
+
+import (
+	"fmt"
+	"path"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"github.com/fieldse/gist-editor/internal/logger"
+	"github.com/fieldse/gist-editor/internal/session"
+)
+
+// sessionState returns the saved session, loaded from the config dir on first use.
+// An unreadable session file is logged, and replaced with an empty session.
+func (cfg *AppConfig) sessionState() *session.State {
+	if cfg.session != nil {
+		return cfg.session
+	}
+	cfg.session = &session.State{}
+	fp, err := sessionFile()
+	if err == nil {
+		var s session.State
+		if s, err = session.Load(fp); err == nil {
+			cfg.session = &s
+		}
+	}
+	if err != nil {
+		logger.Error("load session failed", err)
+	}
+	return cfg.session
+}
+
+// saveSession writes the session to the config dir
+func (cfg *AppConfig) saveSession() {
+	fp, err := sessionFile()
+	if err == nil {
+		err = session.Save(fp, *cfg.sessionState())
+	}
+	if err != nil {
+		logger.Error("save session failed", err)
+	}
+}
+
+// sessionFile returns the path of the session file
+func sessionFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, session.FileName), nil
+}
+
+// currentDocument returns the open document, if it is a saved local file or gist
+func (cfg *AppConfig) currentDocument() (session.Document, bool) {
+	return cfg.document(cfg.CurrentFile, cfg.Editor)
+}
+
+// document returns a document shown in the editor ed, if it is a saved local file or gist
+func (cfg *AppConfig) document(f *GistFile, ed *Editor) (session.Document, bool) {
+	switch {
+	case !f.isOpen:
+		return session.Document{}, false
+	case f.isLocal:
+		d := session.Document{Kind: session.KindFile, Title: ed.Title, LocalURI: f.localURI}
+		return d, d.Valid() == nil
+	}
+	d := session.Document{Kind: session.KindGist, Title: f.Gist.Title(), GistID: f.Gist.ID, Account: cfg.GithubConfig.Name}
+	return d, d.Valid() == nil
+}
+
+// addRecent adds the open document to the recently used documents
+func (cfg *AppConfig) addRecent() {
+	cfg.addRecentFile(cfg.CurrentFile, cfg.Editor)
+}
+
+// addRecentFile adds a document shown in the editor ed to the recently used documents
+func (cfg *AppConfig) addRecentFile(f *GistFile, ed *Editor) {
+	d, ok := cfg.document(f, ed)
+	if !ok {
+		return
+	}
+	cfg.sessionState().AddRecent(d)
+	cfg.saveSession()
+	cfg.MainWindow.RefreshRecent()
+}
+
+// ClearRecent empties the list of recently used documents
+func (cfg *AppConfig) ClearRecent() {
+	cfg.sessionState().Recent = nil
+	cfg.saveSession()
+	cfg.MainWindow.RefreshRecent()
+}
+
+// recentLabel returns the label of a recently used document in menus and lists
+func recentLabel(d session.Document) string {
+	if d.Kind == session.KindGist {
+		return fmt.Sprintf("%s (gist, %s)", d.Title, d.Account)
+	}
+	if uri, err := storage.ParseURI(d.LocalURI); err == nil && uri.Scheme() == "file" {
+		return fmt.Sprintf("%s (%s)", d.Title, path.Dir(uri.Path()))
+	}
+	return d.Title
+}
+
+// OpenRecent opens a recently used document in a new tab
+func (cfg *AppConfig) OpenRecent(d session.Document) {
+	if err := cfg.openDocument(d); err != nil {
+		logger.Error("open recent document failed", err)
+		dialog.ShowError(fmt.Errorf("opening %s failed: %w", d.Title, err), cfg.MainWindow.Window)
+	}
+}
+
+// openDocument opens a local file or gist in a new tab, or shows its tab if it is already open.
+// Files that can't be read are removed from the recently used documents.
+func (cfg *AppConfig) openDocument(d session.Document) error {
+	if d.Kind == session.KindGist {
+		if d.Account != cfg.GithubConfig.Name {
+			return fmt.Errorf("the gist belongs to the account %s: switch to it to open the gist", d.Account)
+		}
+		cfg.openGist(d.GistID)
+		return nil
+	}
+	data, uri, err := readURI(d.LocalURI)
+	if err != nil {
+		cfg.sessionState().RemoveRecent(d)
+		cfg.saveSession()
+		cfg.MainWindow.RefreshRecent()
+		return err
+	}
+	cfg.showLocalFile(uri, data)
+	return nil
+}
+
+// saveOpenDocuments saves the open documents and the state of their editors to the
+// session, if the session is restored on the next launch
+func (cfg *AppConfig) saveOpenDocuments() {
+	s := cfg.sessionState()
+	s.Open = nil
+	active := cfg.EditWindow.Active()
+	for _, t := range cfg.EditWindow.Tabs() {
+		cfg.withTab(t, func() {
+			d, ok := cfg.currentDocument()
+			if !ok || !cfg.Settings.Preferences.RestoreSession {
+				return
+			}
+			row, column := cfg.Editor.Cursor()
+			preview := cfg.Editor.previewEditContainer
+			s.Open = append(s.Open, session.OpenDocument{
+				Document:      d,
+				Active:        t == active,
+				ActiveFile:    cfg.Editor.activeFile,
+				CursorRow:     row,
+				CursorColumn:  column,
+				Preview:       preview.PreviewIsVisible(),
+				PreviewOffset: preview.Content.Offset,
+			})
+		})
+	}
+	cfg.saveSession()
+}
+
+// RestoreSession reopens the documents that were open when the app was last closed,
+// with their cursor positions and preview, if enabled in the preferences.
+// Local files are reopened first; gists are loaded in the background, in turn.
+func (cfg *AppConfig) RestoreSession() {
+	if !cfg.Settings.Preferences.RestoreSession {
+		return
+	}
+	var active *Tab
+	var gists []session.OpenDocument
+	for _, d := range cfg.sessionState().Open {
+		if d.Kind == session.KindGist && d.Account == cfg.GithubConfig.Name {
+			gists = append(gists, d)
+			continue
+		}
+		if err := cfg.openDocument(d.Document); err != nil {
+			logger.Error("reopen document failed", err)
+			continue
+		}
+		if t := cfg.restoreDocument(d); t != nil && d.Active {
+			active = t
+		}
+	}
+	if active != nil {
+		cfg.EditWindow.Select(active)
+	}
+	if len(gists) == 0 {
+		return
+	}
+	client := cfg.GithubClient()
+	go func() {
+		for _, d := range gists {
+			g, err := client.GetGist(d.GistID)
+			if err != nil {
+				logger.Error("reopen gist failed", err)
+				continue
+			}
+			cfg.showGist(g)
+			if t := cfg.restoreDocument(d); t != nil && d.Active {
+				active = t
+			}
+		}
+		if active != nil && cfg.EditWindow.tabWith(active.File) != nil {
+			cfg.EditWindow.Select(active)
+		}
+	}()
+}
+
+// restoreDocument restores the editor state of a reopened document, if it is the open
+// document, and returns its tab
+func (cfg *AppConfig) restoreDocument(d session.OpenDocument) *Tab {
+	if opened, ok := cfg.currentDocument(); !ok || !opened.Same(d.Document) {
+		return nil
+	}
+	if d.ActiveFile != "" && cfg.CurrentFile.Gist.FileIndex(d.ActiveFile) >= 0 {
+		cfg.Editor.SelectFile(d.ActiveFile)
+	}
+	cfg.Editor.SetCursor(d.CursorRow, d.CursorColumn)
+	cfg.Editor.previewEditContainer.SetPreview(d.Preview, d.PreviewOffset)
+	logger.Info("reopened %s from the last session", d.Title)
+	return cfg.EditWindow.Active()
+}
@@ -0,0 +1,194 @@
+// Edit window, showing each open document in a tab
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"github.com/fieldse/gist-editor/internal/github"
+)
+
+// Tab is a document open in the edit window, with its own editor
+type Tab struct {
+	File   *GistFile
+	Editor *Editor
+}
+
+// EditWindow is the edit window. Each open document has a tab; the active tab's
+// document and editor are the app's CurrentFile and Editor.
+type EditWindow struct {
+	window fyne.Window
+	tabs   *container.DocTabs
+	list   []*Tab // the open tabs, in display order
+	cfg    *AppConfig
+}
+
+// New creates the edit window, with an empty tab
+func (e EditWindow) New(cfg *AppConfig) *EditWindow {
+	a := *cfg.App
+	w := a.NewWindow("Edit Gist")
+	w.Resize(fyne.NewSize(800, 600))
+	w.SetCloseIntercept(cfg.CloseAllFiles) // keep the window for reuse, and check for unsaved changes
+	ew := &EditWindow{window: w, cfg: cfg}
+	ew.tabs = container.NewDocTabs()
+	ew.tabs.OnSelected = func(item *container.TabItem) {
+		if t := ew.tabOf(item); t != nil && t.Editor != cfg.Editor {
+			ew.Select(t)
+			cfg.checkChangedFiles()
+			cfg.autosaveAll()
+		}
+	}
+	ew.tabs.CloseIntercept = func(item *container.TabItem) {
+		if t := ew.tabOf(item); t != nil {
+			ew.Select(t)
+			cfg.CloseFile()
+		}
+	}
+	w.SetContent(ew.tabs)
+	w.CenterOnScreen()
+	ew.Select(ew.add())
+	return ew
+}
+
+// Show shows the edit window
+func (e *EditWindow) Show() {
+	e.window.Show()
+}
+
+// Hide hides the edit window
+func (e *EditWindow) Hide() {
+	e.window.Hide()
+}
+
+// Tabs returns the open tabs, in display order
+func (e *EditWindow) Tabs() []*Tab {
+	return append([]*Tab(nil), e.list...)
+}
+
+// Active returns the active tab
+func (e *EditWindow) Active() *Tab {
+	return e.tabOf(e.tabs.Selected())
+}
+
+// Select makes the tab active, so that its document is the open document
+func (e *EditWindow) Select(t *Tab) {
+	cfg := e.cfg
+	cfg.CurrentFile = t.File
+	cfg.Editor = t.Editor
+	if e.tabs.Selected() != t.Editor.tab {
+		e.tabs.Select(t.Editor.tab)
+	}
+	t.Editor.updateWindowTitle()
+	cfg.MainWindow.SetCanSave(t.File.isOpen && !t.File.readOnly)
+}
+
+// find returns the tab of the first open document matching fn, or nil if there is none
+func (e *EditWindow) find(fn func(f *GistFile) bool) *Tab {
+	for _, t := range e.list {
+		if t.File.isOpen && fn(t.File) {
+			return t
+		}
+	}
+	return nil
+}
+
+// tabWith returns the tab holding the document, or nil if the document has been closed
+func (e *EditWindow) tabWith(f *GistFile) *Tab {
+	for _, t := range e.list {
+		if t.File == f {
+			return t
+		}
+	}
+	return nil
+}
+
+// add adds a tab with an empty document and a new editor
+func (e *EditWindow) add() *Tab {
+	ed := Editor{}.New(e.cfg, e.window)
+	ed.tab = container.NewTabItem(ed.Title, ed.content)
+	t := &Tab{File: &GistFile{Gist: &github.Gist{}}, Editor: ed}
+	e.list = append(e.list, t)
+	e.tabs.Append(ed.tab)
+	return t
+}
+
+// remove removes a tab, and makes its neighbour active. The last tab is never removed.
+func (e *EditWindow) remove(t *Tab) {
+	i := e.index(t)
+	if i < 0 || len(e.list) < 2 {
+		return
+	}
+	e.list = append(e.list[:i], e.list[i+1:]...)
+	if i == len(e.list) {
+		i--
+	}
+	e.Select(e.list[i])
+	e.tabs.Remove(t.Editor.tab)
+}
+
+// index returns the position of the tab, or -1 if it isn't open
+func (e *EditWindow) index(t *Tab) int {
+	for i, x := range e.list {
+		if x == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// tabOf returns the tab of a tab item, or nil if none
+func (e *EditWindow) tabOf(item *container.TabItem) *Tab {
+	for _, t := range e.list {
+		if t.Editor.tab == item {
+			return t
+		}
+	}
+	return nil
+}
+
+// openTab shows a document in a new tab, or in the active tab if it is empty, and makes it the open document
+func (cfg *AppConfig) openTab(f *GistFile) {
+	t := cfg.EditWindow.Active()
+	if t == nil || t.File.isOpen {
+		t = cfg.EditWindow.add()
+	}
+	t.File = f
+	cfg.EditWindow.Select(t)
+}
+
+// withTab calls fn with the tab's document as the open document, without making
+// the tab active, for actions on documents in the background
+func (cfg *AppConfig) withTab(t *Tab, fn func()) {
+	file, editor := cfg.CurrentFile, cfg.Editor
+	cfg.CurrentFile, cfg.Editor = t.File, t.Editor
+	defer func() {
+		if t.Editor != editor {
+			cfg.CurrentFile, cfg.Editor = file, editor
+		}
+	}()
+	fn()
+}
+
+// showTab makes the tab of an open document matching fn active, and shows the edit window.
+// Returns false if no document matches.
+func (cfg *AppConfig) showTab(fn func(f *GistFile) bool) bool {
+	t := cfg.EditWindow.find(fn)
+	if t == nil {
+		return false
+	}
+	cfg.EditWindow.Select(t)
+	cfg.ShowEditWindow()
+	return true
+}
+
+// CloseAllFiles closes every open document and the edit window, after checking for unsaved changes
+func (cfg *AppConfig) CloseAllFiles() {
+	cfg.confirmDiscardAll(cfg.closeAllFiles)
+}
+
+// closeAllFiles closes every open document, discarding any unsaved changes
+func (cfg *AppConfig) closeAllFiles() {
+	for len(cfg.EditWindow.list) > 1 || cfg.CurrentFile.isOpen {
+		cfg.closeFile()
+	}
+	cfg.EditWindow.Hide()
+}
@@ -1,10 +1,18 @@
 package ui
 
 import (
+	"fmt"
+	"time"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"github.com/fieldse/gist-editor/internal/config"
 	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/logger"
+	"github.com/fieldse/gist-editor/internal/recovery"
+	"github.com/fieldse/gist-editor/internal/session"
 )
 
 // Basic app structure, with windows and other data to be passed around
@@ -12,19 +20,37 @@ type AppConfig struct {
 	App                  *fyne.App
 	MainWindow           MainWindow
 	ListWindow           *ListView
-	Editor               *Editor
-	CurrentFile          *GistFile
+	EditWindow           *EditWindow
+	Editor               *Editor   // the editor of the active tab
+	CurrentFile          *GistFile // the document of the active tab
 	GithubConfig         *github.GithubConfig
+	Settings             *config.Config // the settings loaded from the config file
 	GithubSettingsWindow *GithubSettingsWindow
+	HistoryWindow        *HistoryWindow
+	PreferencesWindow    *PreferencesWindow
+	githubUser           *github.User              // the user the active account's token belongs to, loaded on demand
+	githubClients        map[string]*github.Client // the API client of each account, kept to reuse their response caches
+	autosaveInterval     time.Duration             // how long documents may have unsaved changes before they are autosaved; 0 if autosave is off
+	journal              *recovery.Journal         // the crash recovery journal, opened on demand
+	pending              *journalQueue             // snapshots of unsaved documents, to be written to the journal
+	watcher              *fileWatcher              // watches the open local file for changes by other programs; nil until a file is opened
+	fileChangedDialog    dialog.Dialog             // the open dialog about a change on disk, if any
+	changedFiles         []string                  // open files changed on disk, waiting for the user's choice about another file
+	pendingSaves         []func()                  // saves of local files waiting for the user's choice about a change on disk
+	session              *session.State            // recently used and last open documents, loaded on demand
 }
 
 // New initializes a new AppConfig instance
 func (AppConfig) New() AppConfig {
 	// Initialize a new Fyne app
 	a := app.New()
+	settings := config.Default()
 	return AppConfig{
-		App:          &a,
-		GithubConfig: &github.GithubConfig{},
+		App:           &a,
+		GithubConfig:  &github.GithubConfig{Name: github.DefaultAccountName},
+		Settings:      &settings,
+		githubClients: map[string]*github.Client{},
+		pending:       newJournalQueue(),
 		CurrentFile: &GistFile{
 			Gist: &github.Gist{},
 		},
@@ -47,11 +73,22 @@ func (cfg *AppConfig) MakeUI() {
 	// Create Gists list window
 	cfg.ListWindow = ListView{}.New(cfg)
 
-	// Create Edit view window, and get the entry editor
-	cfg.Editor = Editor{}.New(cfg)
+	// Create Edit view window, with an empty tab and its editor
+	cfg.EditWindow = EditWindow{}.New(cfg)
 
 	// Create Github token modal
 	cfg.GithubSettingsWindow = GithubSettingsWindow{}.New(cfg)
+
+	// Create gist revision history window
+	cfg.HistoryWindow = newHistoryWindow(cfg)
+
+	// Create preferences modal
+	cfg.PreferencesWindow = PreferencesWindow{}.New(cfg)
+
+	// Check for changes made on disk to the open files when the user returns to the app,
+	// and autosave the open documents when the user leaves it
+	(*cfg.App).Lifecycle().SetOnEnteredForeground(cfg.checkChangedFiles)
+	(*cfg.App).Lifecycle().SetOnExitedForeground(cfg.autosaveAll)
 }
 
 // RunUI starts the application
@@ -59,14 +96,83 @@ func (cfg *AppConfig) RunUI() {
 	cfg.MainWindow.ShowAndRun()
 }
 
-// Show the All Gists list view
+// Show the All Gists list view, and load the user's gists from Github
 func (cfg *AppConfig) ShowListWindow() {
 	cfg.ListWindow.Show()
+	cfg.RefreshGists()
+}
+
+// GithubClient returns the Github API client for the active account.
+// Each account's client is reused while its token and host are unchanged, so that
+// its response cache and rate limit status are kept when switching accounts.
+func (cfg *AppConfig) GithubClient() *github.Client {
+	name := cfg.GithubConfig.Name
+	c := cfg.githubClients[name]
+	if c == nil || c.Token != cfg.GithubConfig.GithubAPIToken || c.BaseURL != cfg.GithubConfig.APIURL() {
+		c = cfg.GithubConfig.NewClient()
+		c.OnRateLimit = func(r github.RateLimit) {
+			if cfg.ListWindow != nil {
+				cfg.ListWindow.SetRateLimit(r)
+			}
+		}
+		cfg.githubClients[name] = c
+	}
+	return c
+}
+
+// githubLogin returns the login name of the user the Github token belongs to,
+// or an empty string if it can't be loaded
+func (cfg *AppConfig) githubLogin() string {
+	if cfg.githubUser == nil {
+		u, err := cfg.GithubClient().GetAuthenticatedUser()
+		if err != nil {
+			logger.Error("get authenticated user failed", err)
+			return ""
+		}
+		cfg.githubUser = &u
+	}
+	return cfg.githubUser.Login
+}
+
+// RefreshGists loads the user's gists, their starred gists, or public gists, from Github
+// into the active section of the list view. Public gists can be browsed without a token.
+func (cfg *AppConfig) RefreshGists() {
+	if cfg.canListGists() {
+		cfg.ListWindow.Refresh()
+	}
+}
+
+// ReloadGists loads the active section of the list view from Github again in full,
+// removing gists that were deleted or unstarred
+func (cfg *AppConfig) ReloadGists() {
+	if cfg.canListGists() {
+		cfg.ListWindow.Reload()
+	}
+}
+
+// canListGists returns whether the active section of the list view can be loaded, and
+// tells the user to set a token if not. Public gists can be browsed without a token.
+func (cfg *AppConfig) canListGists() bool {
+	if cfg.GithubConfig.GithubAPIToken == "" && !cfg.ListWindow.BrowseSelected() {
+		dialog.ShowInformation("No Github token", "Set your Github API token in the Github menu to view your gists.", cfg.ListWindow.window)
+		return false
+	}
+	return true
 }
 
 // Show the Edit Gists view
 func (cfg *AppConfig) ShowEditWindow() {
-	cfg.Editor.Show()
+	cfg.EditWindow.Show()
+}
+
+// Show the revision history of the gist open in the editor
+func (cfg *AppConfig) ShowHistoryWindow() {
+	g := cfg.CurrentFile.Gist
+	if cfg.CurrentFile.isLocal || g.IsNew() {
+		dialog.ShowInformation("History", "Revision history is available once the gist has been saved to Github.", cfg.Editor.editWindow)
+		return
+	}
+	cfg.HistoryWindow.Show(cfg, g.ID)
 }
 
 // Show the Github Token modal
@@ -75,29 +181,63 @@ func (cfg *AppConfig) ShowGithubTokenModal() {
 	w.Show()
 }
 
-// Exit the application
+// Exit the application, after checking for unsaved changes.
+// The open documents are saved to the session, to be reopened on the next launch.
 func (cfg *AppConfig) Exit() {
-	cfg.MainWindow.Close()
+	cfg.confirmDiscardAll(func() {
+		cfg.saveOpenDocuments()
+		cfg.MainWindow.Close()
+	})
 }
 
-// NewFile opens a new empty markdown editor
+// NewFile opens a new empty markdown editor tab for a gist, which is created on Github when first saved
 func (cfg *AppConfig) NewFile() {
-	cfg.MainWindow.SetCanSave(true)
-	// g := github.Gist{}.New("New Gist.md", "Enter your content here...")
-	g := github.Gist{}.New("New Gist.md", "example line 1\nexample line 2\nexample line 3\nexample line 4\nexample line 5") // fixme: replace
-	cfg.CurrentFile = &GistFile{
-		isLocal:  true,
+	prefs := cfg.Settings.Preferences
+	g := github.Gist{}.New(prefs.NewGistFilename, prefs.NewGistContent)
+	g.Public = prefs.NewGistPublic
+	cfg.openTab(&GistFile{
+		isLocal:  false,
 		isOpen:   true,
 		isDirty:  false,
 		localURI: "",
 		Gist:     &g,
-	}
-	cfg.Editor.SetContent(g.Content)
-	cfg.Editor.Title = "New Gist"
+	})
+	cfg.MainWindow.SetCanSave(true)
+	cfg.Editor.SetGist(cfg.CurrentFile.Gist, true)
+	cfg.Editor.SetTitle("New Gist")
 	cfg.ShowEditWindow()
 }
 
-// OpenFile opens a local markdown file
+// RestoreRevision replaces the files of the open gist with those of an older
+// revision, saved to Github as a new revision. done is called with the result
+// once the request completes.
+func (cfg *AppConfig) RestoreRevision(revision github.Gist, done func(error)) {
+	cfg.Editor.SyncContent()
+	f, g := cfg.CurrentFile, gistSnapshot(cfg.CurrentFile.Gist)
+	go func() {
+		saved, err := cfg.GithubClient().RestoreRevision(g, revision)
+		if err != nil {
+			logger.Error("restore revision failed", err)
+			done(fmt.Errorf("restoring revision failed: %w", err))
+			return
+		}
+		cfg.gistSaved(f, g, saved)
+		logger.Info("restored gist %s", saved.ID)
+		done(nil)
+	}()
+}
+
+// currentGistContent returns the open gist, including unsaved changes in the
+// editor, or nil if no gist is open
+func (cfg *AppConfig) currentGistContent() *github.Gist {
+	if !cfg.CurrentFile.isOpen || cfg.CurrentFile.isLocal {
+		return nil
+	}
+	cfg.Editor.SyncContent()
+	return cfg.CurrentFile.Gist
+}
+
+// OpenFile opens a local markdown file in a new tab
 func (cfg *AppConfig) OpenFile() {
 	d := dialog.NewFileOpen(openFile, cfg.MainWindow.Window)
 	d.SetFilter(filter)
@@ -105,31 +245,363 @@ func (cfg *AppConfig) OpenFile() {
 	d.Show()
 }
 
-// SaveFile saves the currently open markdown file locally to disk
+// OpenGist loads a gist from Github by ID, and opens it in a new tab.
+// A gist that is already open is shown in its tab.
+func (cfg *AppConfig) OpenGist(id string) {
+	cfg.openGist(id)
+}
+
+// openGist opens a gist loaded from Github in a new tab, or shows its tab if it is already open.
+// The gist is loaded in the background.
+func (cfg *AppConfig) openGist(id string) {
+	if cfg.showTab(isGist(id)) {
+		return
+	}
+	w := cfg.ListWindow.window
+	go func() {
+		g, err := cfg.GithubClient().GetGist(id)
+		if err != nil {
+			logger.Error("open gist failed", err)
+			dialog.ShowError(fmt.Errorf("opening gist failed: %w", err), w)
+			return
+		}
+		cfg.showGist(g)
+	}()
+}
+
+// isGist returns a match for the tab of the gist with the given ID
+func isGist(id string) func(f *GistFile) bool {
+	return func(f *GistFile) bool { return !f.isLocal && f.Gist.ID == id }
+}
+
+// showGist opens a gist fetched from Github in a new tab, or shows its tab if it was opened meanwhile
+func (cfg *AppConfig) showGist(g github.Gist) {
+	if cfg.showTab(isGist(g.ID)) {
+		return // opened again while loading
+	}
+	// Other users' gists are opened read-only
+	readOnly := g.AuthorId != "" && g.AuthorId != cfg.githubLogin()
+	cfg.openTab(&GistFile{
+		Gist:     &g,
+		isOpen:   true,
+		readOnly: readOnly,
+	})
+	cfg.Editor.SetGist(cfg.CurrentFile.Gist, !readOnly)
+	cfg.Editor.SetReadOnly(readOnly)
+	cfg.Editor.SetTitle(g.Title())
+	cfg.addRecent()
+	cfg.MainWindow.SetCanSave(!readOnly)
+	cfg.ShowEditWindow()
+}
+
+// ForkGist copies another user's gist into the user's account, and opens the copy in the editor
+func (cfg *AppConfig) ForkGist(id string) {
+	w := cfg.Editor.editWindow
+	go func() {
+		fork, err := cfg.GithubClient().ForkGist(id)
+		if err != nil {
+			logger.Error("fork gist failed", err)
+			dialog.ShowError(fmt.Errorf("forking gist failed: %w", err), w)
+			return
+		}
+		logger.Info("forked gist %s to %s", id, fork.ID)
+		cfg.OpenGist(fork.ID)
+	}()
+}
+
+// SaveFile saves the currently open markdown file, either locally to disk,
+// or to Github if it is a gist
 func (cfg *AppConfig) SaveFile() {
-	cfg.CurrentFile.Save()
+	cfg.saveFileThen(nil)
+}
+
+// saveFileThen saves the open document, and calls done, if not nil, once it has been saved.
+// done is not called if saving fails or is cancelled.
+func (cfg *AppConfig) saveFileThen(done func()) {
+	if cfg.CurrentFile.readOnly {
+		dialog.ShowInformation("Read-only gist", "This gist belongs to another user. Fork it to edit your own copy.", cfg.Editor.editWindow)
+		return
+	}
+	if cfg.CurrentFile.isLocal {
+		cfg.saveLocalFile(done)
+		return
+	}
+	cfg.saveGistThen(done)
+}
+
+// saveLocalFile writes the open local file to disk. Files without a location are saved with Save As.
+func (cfg *AppConfig) saveLocalFile(done func()) {
+	if cfg.CurrentFile.localURI == "" {
+		cfg.saveFileAsThen(done)
+		return
+	}
+	cfg.checkChangedFiles() // ask before replacing changes made on disk
+	if cfg.fileChangedDialog != nil {
+		// Save once the user has chosen what to do with the changes on disk
+		f := cfg.CurrentFile
+		cfg.pendingSaves = append(cfg.pendingSaves, func() {
+			if t := cfg.EditWindow.tabWith(f); t != nil {
+				cfg.EditWindow.Select(t)
+				cfg.saveLocalFile(done)
+			}
+		})
+		return
+	}
+	cfg.Editor.SyncContent()
+	if err := cfg.CurrentFile.Save(); err != nil {
+		logger.Error("save file failed", err)
+		dialog.ShowError(fmt.Errorf("saving file failed: %w", err), cfg.Editor.editWindow)
+		return
+	}
+	cfg.setDirty(false)
+	if done != nil {
+		done()
+	}
+}
+
+// SaveGist saves the current gist to Github, creating the gist if it is new.
+// All file changes are saved in a single update.
+// New public gists are only created after the user confirms.
+func (cfg *AppConfig) SaveGist() {
+	cfg.saveGistThen(nil)
+}
+
+// saveGistThen saves the current gist to Github, and calls done, if not nil, once it has been saved
+func (cfg *AppConfig) saveGistThen(done func()) {
+	w := cfg.Editor.editWindow
+	cfg.Editor.SyncContent()
+	f, g := cfg.CurrentFile, gistSnapshot(cfg.CurrentFile.Gist)
+	saveGist := cfg.saveGist
+	if f.repo != nil {
+		saveGist = cfg.saveGitGist
+	}
+	save := func() {
+		saveGist(f, g, func(err error) {
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			if t := cfg.EditWindow.tabWith(f); t != nil && done != nil {
+				cfg.EditWindow.Select(t)
+				done()
+			}
+		})
+	}
+	if g.IsNew() && g.Public {
+		msg := "Public gists are visible to everyone, and can't be made secret later.\nCreate a public gist?"
+		dialog.ShowConfirm("Create public gist?", msg, func(ok bool) {
+			if ok {
+				save()
+			}
+		}, w)
+		return
+	}
+	save()
+}
+
+// saveGist creates or updates the gist of a document on Github in the background, and
+// updates the document's editor. done is called with the result once the request completes.
+func (cfg *AppConfig) saveGist(f *GistFile, g github.Gist, done func(err error)) {
+	f.saving = true
+	go func() {
+		var saved github.Gist
+		var err error
+		if g.IsNew() {
+			saved, err = cfg.GithubClient().CreateGist(g)
+		} else {
+			saved, err = cfg.GithubClient().UpdateGist(g)
+		}
+		f.saving = false
+		if err != nil {
+			logger.Error("save gist failed", err)
+			done(fmt.Errorf("saving gist failed: %w", err))
+			return
+		}
+		cfg.gistSaved(f, g, saved)
+		logger.Info("saved gist %s", saved.ID)
+		done(nil)
+	}()
+}
+
+// gistSaved stores a gist saved to Github in its document, sent being the content that was saved.
+// If the content round-tripped unchanged, the editor keeps its text, undo history and any
+// changes made while saving; otherwise the saved gist is reloaded into the editor.
+func (cfg *AppConfig) gistSaved(f *GistFile, sent github.Gist, saved github.Gist) {
+	f.lastSaved = time.Now()
+	t := cfg.EditWindow.tabWith(f)
+	if t == nil {
+		f.Gist = &saved
+		return
+	}
+	ed := t.Editor
+	f.Gist = &saved
+	if !sameContent(sent, saved) {
+		ed.ReloadGist(f.Gist)
+		cfg.setFileDirty(f, ed, false)
+	} else {
+		ed.UpdateGist(f.Gist)
+		if sameContent(sent, *f.Gist) {
+			cfg.setFileDirty(f, ed, false)
+		} else {
+			f.dirtySince = time.Now() // edited while saving
+		}
+	}
+	cfg.addRecentFile(f, ed)
+}
+
+// gistSnapshot returns a copy of a gist whose files are not changed by later edits
+func gistSnapshot(g *github.Gist) github.Gist {
+	res := *g
+	res.Files = append([]github.File(nil), g.Files...)
+	return res
+}
+
+// sameContent returns whether two versions of a gist have the same description and files
+func sameContent(a github.Gist, b github.Gist) bool {
+	if a.Description != b.Description || len(a.Files) != len(b.Files) {
+		return false
+	}
+	for _, x := range a.Files {
+		y := b.File(x.Filename)
+		if y == nil || y.Content != x.Content || y.Truncated != x.Truncated {
+			return false
+		}
+	}
+	return true
 }
 
-// SaveFileAs saves the currently open markdown file locally to disk with a new filename
+// SaveFileAs saves the open file to a new local file, chosen with a file save dialog.
+// The active file of a gist is saved as a new local document, opened in a new tab; the gist stays open.
 func (cfg *AppConfig) SaveFileAs() {
-	cfg.CurrentFile.SaveAs()
+	cfg.saveFileAsThen(nil)
+}
+
+// saveFileAsThen saves the open file to a new local file, and calls done, if not nil, once it has been saved
+func (cfg *AppConfig) saveFileAsThen(done func()) {
+	w := cfg.Editor.editWindow
+	d := dialog.NewFileSave(func(wr fyne.URIWriteCloser, err error) {
+		if err != nil {
+			logger.Error("save file dialog failed", err)
+			dialog.ShowError(err, w)
+			return
+		}
+		if wr == nil {
+			logger.Debug("save as was canceled")
+			return
+		}
+		// The dialog has already created or truncated the file, so it is written through
+		// the dialog's writer; it can't be replaced atomically
+		if err := cfg.saveFileAs(wr); err != nil {
+			logger.Error("save file as failed", err)
+			dialog.ShowError(fmt.Errorf("saving file failed: %w", err), w)
+			return
+		}
+		if done != nil {
+			done()
+		}
+	}, w)
+	d.SetFilter(filter)
+	name := cfg.Editor.activeFile
+	if cfg.CurrentFile.localURI != "" {
+		if uri, err := storage.ParseURI(cfg.CurrentFile.localURI); err == nil {
+			name = uri.Name()
+			if dir, err := storage.Parent(uri); err == nil {
+				if l, err := storage.ListerForURI(dir); err == nil {
+					d.SetLocation(l)
+				}
+			}
+		}
+	}
+	if name != "" {
+		d.SetFileName(name)
+	}
+	d.Resize(fyne.NewSize(800, 600))
+	d.Show()
+}
+
+// saveFileAs writes the open file through the writer of a new URI, which becomes its location.
+// For a gist, the active file is written, and opened from the new location in a new tab.
+func (cfg *AppConfig) saveFileAs(w fyne.URIWriteCloser) error {
+	uri := w.URI()
+	cfg.Editor.SyncContent()
+	f := cfg.CurrentFile
+	if !f.isLocal {
+		data := []byte(cfg.Editor.Content())
+		if err := writeTo(w, data); err != nil {
+			return err
+		}
+		cfg.showLocalFile(uri, data)
+		return nil
+	}
+	if err := f.SaveAs(w); err != nil {
+		return err
+	}
+	cfg.Editor.ReloadGist(f.Gist)
+	cfg.Editor.SetTitle(uri.Name())
+	cfg.setDirty(false)
+	cfg.watchFiles()
+	cfg.addRecent()
+	cfg.MainWindow.SetCanSave(true)
+	return nil
+}
+
+// setDirty sets whether the open document has unsaved changes
+func (cfg *AppConfig) setDirty(b bool) {
+	cfg.setFileDirty(cfg.CurrentFile, cfg.Editor, b)
+}
+
+// setFileDirty sets whether a document, shown in the editor ed, has unsaved changes
+func (cfg *AppConfig) setFileDirty(f *GistFile, ed *Editor, b bool) {
+	if b && !f.isDirty {
+		f.dirtySince = time.Now()
+	}
+	f.isDirty = b
+	ed.SetDirty(b)
+	if b {
+		cfg.snapshotJournal(f, ed)
+	} else {
+		cfg.discardJournal(f)
+	}
 }
 
-// CloseFile closes the currently open markdown file and closes the editor window
+// CloseFile closes the document of the active tab, after checking for unsaved changes.
+// The edit window is closed with its last document.
 func (cfg *AppConfig) CloseFile() {
+	cfg.confirmDiscard(cfg.closeFile)
+}
+
+// closeFile closes the open document and its tab, discarding any unsaved changes.
+// The last tab is kept, empty, for the next document, and the edit window is hidden.
+func (cfg *AppConfig) closeFile() {
+	cfg.discardJournal(cfg.CurrentFile)
+	if t := cfg.EditWindow.Active(); len(cfg.EditWindow.list) > 1 && t != nil {
+		cfg.EditWindow.remove(t)
+		cfg.watchFiles()
+		return
+	}
 	cfg.MainWindow.SetCanSave(false)
 	cfg.Editor.Clear() // clear the editor text and title
 	cfg.CurrentFile.Close()
-	cfg.Editor.Hide()
+	cfg.watchFiles()
+	cfg.EditWindow.Hide()
 }
 
-// LoadConfig reads and stores the config settings from the config file
+// LoadConfig reads and stores the config settings from the config file,
+// and applies the preferences
 func (cfg *AppConfig) LoadConfig() error {
-	return cfg.GithubSettingsWindow.Load(cfg)
+	err := cfg.GithubSettingsWindow.Load(cfg)
+	cfg.applyPreferences()
+	cfg.MainWindow.RefreshRecent()
+	return err
 }
 
 func StartUI() {
 	cfg.MakeUI()
-	cfg.LoadConfig()
+	if err := cfg.LoadConfig(); err != nil {
+		dialog.ShowError(fmt.Errorf("loading settings failed: %w", err), cfg.MainWindow.Window)
+	}
+	cfg.RestoreSession()
+	cfg.OfferRecovery()
+	cfg.startJournal()
 	cfg.RunUI()
 }
@@ -1,11 +1,41 @@
 package ui
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/storage"
+	"github.com/fieldse/gist-editor/internal/config"
+	"github.com/fieldse/gist-editor/internal/github"
+	"github.com/fieldse/gist-editor/internal/secrets"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// Tests use a temporary config dir and an in-memory secret store, so the user's
+// settings and credentials are never read or changed
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gist-editor-test")
+	if err != nil {
+		panic(err)
+	}
+	ConfigDir = dir
+	legacyConfigDir = ""
+	GITHUB_CONFIG_FILE = ""
+	tokenStore = secrets.NewMemoryStore()
+	ghHostsFile = ""
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		os.Unsetenv(name)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func Test_runUI(t *testing.T) {
 
 	a := AppConfig{}.New()
@@ -26,3 +56,704 @@ func Test_ReadConfig(t *testing.T) {
 	assert.Nil(t, res, "read config should succeed")
 
 }
+
+func Test_LoadConfig(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	host := github.EnterpriseConfig("github.example.com")
+	require.Nil(t, saveHostSettings(&a, host))
+
+	b := AppConfig{}.New()
+	b.MakeUI()
+	require.Nil(t, b.LoadConfig())
+	assert.Equal(t, host.APIBaseURL, b.GithubConfig.APIBaseURL)
+	assert.Equal(t, host.APIBaseURL, b.Settings.GithubConfig().APIBaseURL)
+	assert.True(t, b.GithubSettingsWindow.signIn.Disabled(), "sign-in needs an OAuth app client ID")
+	assert.True(t, b.GithubSettingsWindow.signInNote.Visible())
+	b.GithubSettingsWindow.clientField.SetText("Iv1.abc123")
+	assert.False(t, b.GithubSettingsWindow.signIn.Disabled(), "entering a client ID should enable sign-in")
+	assert.False(t, b.GithubSettingsWindow.signInNote.Visible())
+	require.Nil(t, saveHostSettings(&b, github.GithubConfig{}))
+}
+
+func Test_saveGithubSettings(t *testing.T) {
+	defer func() { tokenStore = secrets.NewMemoryStore() }()
+	tokenStore = secrets.NewMemoryStore()
+	a := AppConfig{}.New()
+	a.MakeUI()
+	require.Nil(t, a.LoadConfig())
+	defer saveHostSettings(&a, github.GithubConfig{})
+
+	// Host settings are saved without a token, for anonymous browsing
+	t.Setenv("GH_ENTERPRISE_TOKEN", "ghp_env")
+	host := github.EnterpriseConfig("github.example.com")
+	host.Name = a.GithubConfig.Name
+	require.Nil(t, saveGithubSettings(&a, host, ""))
+	assert.Equal(t, host.APIBaseURL, a.Settings.GithubConfig().APIBaseURL)
+	assert.Equal(t, "ghp_env", a.GithubConfig.GithubAPIToken)
+	_, err := tokenStore.Get(tokenKey(host.Name))
+	assert.ErrorIs(t, err, secrets.ErrNotFound, "tokens from other sources should not be copied to the store")
+
+	// A token that can't be saved leaves the host settings unchanged
+	other := github.EnterpriseConfig("other.example.com")
+	other.Name = host.Name
+	assert.NotNil(t, saveGithubSettings(&a, other, "not a token!"))
+	assert.Equal(t, host.APIBaseURL, a.Settings.GithubConfig().APIBaseURL)
+
+	require.Nil(t, saveGithubSettings(&a, host, "ghp_typed"))
+	stored, err := tokenStore.Get(tokenKey(host.Name))
+	require.Nil(t, err)
+	assert.Equal(t, "ghp_typed", stored)
+}
+
+func Test_migrateLegacyToken(t *testing.T) {
+	legacy := GITHUB_CONFIG_FILE
+	defer func() { GITHUB_CONFIG_FILE = legacy; tokenStore = secrets.NewMemoryStore() }()
+	GITHUB_CONFIG_FILE = filepath.Join(t.TempDir(), "github-token.txt")
+	tokenStore = secrets.NewMemoryStore()
+	require.Nil(t, os.WriteFile(GITHUB_CONFIG_FILE, []byte("ghp_legacy123\n"), 0644))
+
+	token, err := readStoredToken("default")
+	require.Nil(t, err)
+	assert.Equal(t, "ghp_legacy123", token)
+	assert.False(t, fileExists(GITHUB_CONFIG_FILE), "the plain text token file should be removed")
+	stored, err := tokenStore.Get(tokenKey("default"))
+	require.Nil(t, err)
+	assert.Equal(t, "ghp_legacy123", stored)
+
+	// Tokens stored before accounts were added are moved to the account
+	require.Nil(t, tokenStore.Set(githubTokenKey, "ghp_unnamed456"))
+	token, err = readStoredToken("work")
+	require.Nil(t, err)
+	assert.Equal(t, "ghp_unnamed456", token)
+	_, err = tokenStore.Get(githubTokenKey)
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+}
+
+func Test_ReadGithubToken(t *testing.T) {
+	defer func() { tokenStore = secrets.NewMemoryStore(); ghHostsFile = "" }()
+	tokenStore = secrets.NewMemoryStore()
+	ghHostsFile = filepath.Join(t.TempDir(), "hosts.yml")
+	require.Nil(t, os.WriteFile(ghHostsFile, []byte("github.com:\n    oauth_token: gho_cli\n"), 0600))
+	host := github.GithubConfig{Name: "default"}
+	all := []string{"store", "env", "gh"}
+
+	tok, err := ReadGithubToken(host, all)
+	require.Nil(t, err)
+	assert.Equal(t, "gho_cli", tok.Value)
+	assert.Equal(t, "gh", tok.Source)
+
+	t.Setenv("GH_TOKEN", "ghp_env")
+	tok, _ = ReadGithubToken(host, all)
+	assert.Equal(t, "ghp_env", tok.Value)
+	assert.Equal(t, "environment variable GH_TOKEN", tok.Describe())
+
+	require.Nil(t, saveToken("default", "ghp_saved"))
+	tok, _ = ReadGithubToken(host, all)
+	assert.Equal(t, "ghp_saved", tok.Value, "saved tokens should come first by default")
+	tok, _ = ReadGithubToken(host, []string{"gh", "store"})
+	assert.Equal(t, "gho_cli", tok.Value, "the configured order should be followed")
+
+	tok, _ = ReadGithubToken(github.EnterpriseConfig("github.example.com"), []string{"env", "gh"})
+	assert.Equal(t, "", tok.Value, "tokens for github.com should not be used for other hosts")
+}
+
+func Test_SwitchAccount(t *testing.T) {
+	defer func() { tokenStore = secrets.NewMemoryStore() }()
+	require.Nil(t, saveToken("default", "ghp_personal"))
+	require.Nil(t, saveToken("work", "ghp_work"))
+	a := AppConfig{}.New()
+	a.MakeUI()
+	require.Nil(t, a.LoadConfig())
+
+	work := accountHost("github.example.com")
+	work.Name = "work"
+	require.Nil(t, a.addAccount(work))
+	assert.Equal(t, "default", a.GithubConfig.Name, "adding an account should not switch to it")
+	personal := a.GithubClient()
+
+	// Switching loads the account's host and token, and its own API client
+	a.SwitchAccount("work")
+	assert.Equal(t, "work", a.GithubConfig.Name)
+	assert.Equal(t, "ghp_work", a.GithubConfig.GithubAPIToken)
+	assert.Equal(t, "https://github.example.com/api/v3", a.GithubClient().BaseURL)
+	assert.Equal(t, "work", a.Settings.ActiveAccount)
+
+	// The previous account's client, and its cache, are kept
+	a.SwitchAccount("default")
+	assert.Same(t, personal, a.GithubClient())
+
+	// Gists with unsaved changes are not closed until the user chooses what to do with them
+	g := github.Gist{}.New("notes.md", "# Notes")
+	a.openTab(&GistFile{Gist: &g, isOpen: true, isDirty: true})
+	a.SwitchAccount("work")
+	assert.Equal(t, "default", a.GithubConfig.Name)
+	assert.True(t, a.CurrentFile.isOpen)
+	a.closeFile()
+	assert.Equal(t, "ghp_personal", a.GithubConfig.GithubAPIToken)
+
+	// Removing the active account switches to the remaining account
+	require.Nil(t, a.switchAccount("work"))
+	require.Nil(t, a.removeAccount("work"))
+	assert.Equal(t, "default", a.GithubConfig.Name)
+	assert.Equal(t, []string{"default"}, a.Settings.GithubAccounts().Names())
+	_, err := tokenStore.Get(tokenKey("work"))
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+}
+
+func Test_EditorSelectFile(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+
+	g := github.Gist{}.New("README.md", "# Readme")
+	g.AddFile("script.sh", "echo hi")
+	a.Editor.SetGist(&g, true)
+	assert.Equal(t, "# Readme", a.Editor.Content(), "first file should be shown")
+
+	// Edits are kept when switching between files
+	a.Editor.editor.SetText("# Edited")
+	a.Editor.SelectFile("script.sh")
+	assert.Equal(t, "echo hi", a.Editor.Content())
+	assert.Equal(t, "# Edited", g.File("README.md").Content)
+}
+
+func Test_EditorMetadata(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+
+	g := github.Gist{}.New("README.md", "# Readme")
+	a.Editor.SetGist(&g, true)
+	assert.False(t, a.Editor.metadata.visibility.Disabled(), "visibility can be set for new gists")
+	a.Editor.metadata.description.SetText("My notes")
+	a.Editor.metadata.visibility.SetSelected(visibilityPublic)
+	assert.Equal(t, "My notes", g.Description)
+	assert.True(t, g.Public)
+
+	saved := github.Gist{ID: "abc123", Description: "Saved", Files: g.Files}
+	a.Editor.SetGist(&saved, true)
+	assert.Equal(t, "Saved", a.Editor.metadata.description.Text)
+	assert.True(t, a.Editor.metadata.visibility.Disabled(), "visibility can't be changed after creation")
+}
+
+func Test_EditorReadOnly(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+
+	g := github.Gist{ID: "abc123", AuthorId: "someone", Files: []github.File{{Filename: "README.md", Content: "# Readme"}}}
+	a.Editor.SetGist(&g, false)
+	a.Editor.SetReadOnly(true)
+	assert.True(t, a.Editor.editor.Disabled(), "read-only gists can't be edited")
+	assert.True(t, a.Editor.saveButton.Disabled())
+	assert.True(t, a.Editor.forkButton.Visible())
+
+	// Opening another gist leaves read-only mode
+	a.Editor.SetGist(&g, true)
+	assert.False(t, a.Editor.editor.Disabled())
+	assert.False(t, a.Editor.forkButton.Visible())
+}
+
+func Test_previewMarkdown(t *testing.T) {
+	g := github.Gist{Description: "Notes", Files: []github.File{
+		{Filename: "README.md", Content: "# Readme"},
+		{Filename: "run.sh", Content: "echo hi"},
+	}}
+	assert.Equal(t, "*Notes*\n\n## README.md\n\n# Readme\n\n## run.sh\n\n```\necho hi\n```\n\n", previewMarkdown(g))
+}
+
+func Test_gistSectionPaging(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+
+	pages := map[int]github.GistPage{
+		1: {Gists: []github.Gist{{ID: "a", UpdatedAt: time.Unix(300, 0)}}, NextPage: 2},
+		2: {Gists: []github.Gist{{ID: "b", UpdatedAt: time.Unix(200, 0)}}},
+	}
+	var since time.Time
+	s := newGistSection(
+		func(o github.ListOptions) (github.GistPage, error) { return pages[o.Page], nil },
+		func(t time.Time) ([]github.Gist, error) {
+			since = t
+			return []github.Gist{{ID: "c", UpdatedAt: time.Unix(400, 0)}}, nil
+		},
+		func(github.Gist) {}, false, nil)
+	waitLoaded := func() {
+		assert.Eventually(t, func() bool { s.mu.Lock(); defer s.mu.Unlock(); return !s.loading }, time.Second, time.Millisecond)
+	}
+
+	s.Refresh() // first refresh loads the first page
+	waitLoaded()
+	assert.Len(t, s.data(), 1)
+
+	s.LoadMore()
+	waitLoaded()
+	assert.Len(t, s.data(), 2)
+	assert.Equal(t, 0, s.nextPage, "all pages should be loaded")
+
+	s.Refresh() // later refreshes only fetch updated gists
+	waitLoaded()
+	assert.Equal(t, time.Unix(300, 0), since)
+	assert.Equal(t, "c", s.data()[0].ID, "updated gists should be merged in")
+	assert.Len(t, s.data(), 3)
+
+	// A full reload loads the pages loaded so far again, removing gists no longer listed
+	pages[1] = github.GistPage{Gists: []github.Gist{{ID: "c", UpdatedAt: time.Unix(400, 0)}}, NextPage: 2}
+	s.Reload()
+	waitLoaded()
+	assert.Equal(t, []string{"c", "b"}, gistIDs(s.data()))
+
+	// Loads started before a reset are discarded
+	release := make(chan struct{})
+	s.fetchPage = func(o github.ListOptions) (github.GistPage, error) {
+		<-release
+		return pages[o.Page], nil
+	}
+	s.Reload()
+	s.Reset()
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, s.data())
+	assert.False(t, s.loaded)
+}
+
+// gistIDs returns the IDs of the gists
+func gistIDs(gists []github.Gist) []string {
+	var ids []string
+	for _, g := range gists {
+		ids = append(ids, g.ID)
+	}
+	return ids
+}
+
+func Test_SavePreferences(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	require.Nil(t, a.LoadConfig())
+	defer func() { require.Nil(t, a.SavePreferences(config.DefaultPreferences())) }()
+
+	prefs := config.DefaultPreferences()
+	prefs.WrapMode = config.WrapWord
+	prefs.NewGistFilename = "notes.md"
+	prefs.NewGistContent = "# Notes"
+	prefs.NewGistPublic = true
+	prefs.WindowSizes.Editor = config.Size{Width: 1000, Height: 700}
+	require.Nil(t, a.SavePreferences(prefs))
+	assert.Equal(t, fyne.TextWrapWord, a.Editor.editor.Wrapping, "preferences should apply to open windows")
+	assert.Equal(t, fyne.NewSize(1000, 700), a.Editor.editWindow.Canvas().Size())
+
+	a.NewFile()
+	assert.Equal(t, "notes.md", a.CurrentFile.Gist.Title())
+	assert.Equal(t, "# Notes", a.Editor.Content())
+	assert.True(t, a.CurrentFile.Gist.Public)
+
+	// Saved preferences are loaded on the next start
+	b := AppConfig{}.New()
+	b.MakeUI()
+	require.Nil(t, b.LoadConfig())
+	assert.Equal(t, prefs, b.Settings.Preferences)
+
+	// The form rejects invalid values
+	b.PreferencesWindow.Show(prefs)
+	b.PreferencesWindow.mainSize.SetText("big")
+	_, err := b.PreferencesWindow.preferences()
+	assert.NotNil(t, err)
+}
+
+func Test_writeFileAtomic(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "notes.md")
+	require.Nil(t, writeFileAtomic(fp, []byte("new")))
+	info, err := os.Stat(fp)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// Existing permissions are kept
+	require.Nil(t, os.Chmod(fp, 0600))
+	require.Nil(t, writeFileAtomic(fp, []byte("updated")))
+	data, _ := os.ReadFile(fp)
+	assert.Equal(t, "updated", string(data))
+	info, _ = os.Stat(fp)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	entries, _ := os.ReadDir(filepath.Dir(fp))
+	assert.Len(t, entries, 1, "no temp files should be left behind")
+}
+
+func Test_SaveLocalFile(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "notes.md")
+	require.Nil(t, os.WriteFile(fp, []byte("# Notes"), 0644))
+
+	g := github.Gist{}.New("notes.md", "# Notes")
+	a.openTab(&GistFile{Gist: &g, isLocal: true, isOpen: true, isDirty: true, localURI: storage.NewFileURI(fp).String()})
+	a.Editor.SetGist(&g, false)
+	a.Editor.SetContent("# Edited")
+	a.SaveFile()
+	data, _ := os.ReadFile(fp)
+	assert.Equal(t, "# Edited", string(data))
+	assert.False(t, a.CurrentFile.isDirty)
+
+	// Save as writes to the new location, which becomes the file's location
+	copyPath := filepath.Join(dir, "copy.md")
+	wr, err := storage.Writer(storage.NewFileURI(copyPath))
+	require.Nil(t, err)
+	require.Nil(t, a.saveFileAs(wr))
+	data, _ = os.ReadFile(copyPath)
+	assert.Equal(t, "# Edited", string(data))
+	assert.Equal(t, storage.NewFileURI(copyPath).String(), a.CurrentFile.localURI)
+	assert.Equal(t, "copy.md", a.Editor.Title)
+	assert.Equal(t, "copy.md - Edit Gist", a.Editor.editWindow.Title())
+
+	// A failed save as is reported, and leaves the file and its location unchanged
+	a.Editor.SetContent("# Changed")
+	assert.NotNil(t, a.saveFileAs(failingWriter{storage.NewFileURI(filepath.Join(dir, "other.md"))}))
+	data, _ = os.ReadFile(copyPath)
+	assert.Equal(t, "# Edited", string(data))
+	assert.Equal(t, storage.NewFileURI(copyPath).String(), a.CurrentFile.localURI)
+	assert.True(t, a.CurrentFile.isDirty)
+}
+
+func Test_SaveGistAs(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	g := github.Gist{}.New("notes.md", "# Notes")
+	g.ID = "abc123"
+	require.Nil(t, g.AddFile("other.md", "# Other"))
+	a.openTab(&GistFile{Gist: &g, isOpen: true})
+	a.Editor.SetGist(&g, true)
+	a.Editor.editor.SetText("# Edited")
+	gistTab := a.EditWindow.Active()
+
+	// The active file is saved and opened in a new tab, and the gist stays open with its changes
+	fp := filepath.Join(t.TempDir(), "notes.md")
+	wr, err := storage.Writer(storage.NewFileURI(fp))
+	require.Nil(t, err)
+	require.Nil(t, a.saveFileAs(wr))
+	data, _ := os.ReadFile(fp)
+	assert.Equal(t, "# Edited", string(data))
+	assert.Equal(t, storage.NewFileURI(fp).String(), a.CurrentFile.localURI)
+	assert.False(t, a.CurrentFile.isDirty)
+	assert.Len(t, a.EditWindow.Tabs(), 2)
+	assert.True(t, gistTab.File.isDirty, "the gist's unsaved changes should be kept")
+	assert.Equal(t, "# Edited", g.File("notes.md").Content)
+}
+
+// failingWriter is a storage writer whose writes fail, as on a full disk
+type failingWriter struct {
+	uri fyne.URI
+}
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, errors.New("no space left on device") }
+func (w failingWriter) Close() error              { return nil }
+func (w failingWriter) URI() fyne.URI             { return w.uri }
+
+func Test_DirtyTracking(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	fp := filepath.Join(t.TempDir(), "notes.md")
+	require.Nil(t, os.WriteFile(fp, []byte("# Notes"), 0644))
+
+	g := github.Gist{}.New("notes.md", "# Notes")
+	a.openTab(&GistFile{Gist: &g, isLocal: true, isOpen: true, localURI: storage.NewFileURI(fp).String()})
+	a.Editor.SetGist(&g, false)
+	a.Editor.SetTitle("notes.md")
+	assert.False(t, a.CurrentFile.isDirty, "loading a document is not a change")
+
+	a.Editor.editor.SetText("# Edited")
+	assert.True(t, a.CurrentFile.isDirty)
+	assert.Equal(t, "*notes.md - Edit Gist", a.Editor.editWindow.Title(), "unsaved changes should be marked")
+
+	// Closing asks before discarding the changes
+	a.CloseFile()
+	assert.True(t, a.CurrentFile.isOpen, "the document should stay open until the user chooses")
+
+	a.SaveFile()
+	assert.False(t, a.CurrentFile.isDirty)
+	assert.Equal(t, "notes.md - Edit Gist", a.Editor.editWindow.Title())
+	a.CloseFile()
+	assert.False(t, a.CurrentFile.isOpen)
+}
+
+func Test_Autosave(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	fp := filepath.Join(t.TempDir(), "notes.md")
+	require.Nil(t, os.WriteFile(fp, []byte("# Notes"), 0644))
+
+	g := github.Gist{}.New("notes.md", "# Notes")
+	a.openTab(&GistFile{Gist: &g, isLocal: true, isOpen: true, localURI: storage.NewFileURI(fp).String()})
+	a.Editor.SetGist(&g, false)
+	a.startAutosave(config.Preferences{Autosave: true, AutosaveInterval: 60})
+
+	// Edits are saved once the document has had unsaved changes for the interval
+	a.Editor.editor.SetText("# Edited")
+	assert.True(t, a.CurrentFile.isDirty, "a new change is not saved yet")
+	a.CurrentFile.dirtySince = time.Now().Add(-time.Minute)
+	a.Editor.editor.SetText("# Edited again")
+	assert.False(t, a.CurrentFile.isDirty)
+	data, _ := os.ReadFile(fp)
+	assert.Equal(t, "# Edited again", string(data))
+
+	// A failing autosave is reported once, and retried after another interval
+	a.CurrentFile.localURI = storage.NewFileURI(filepath.Join(fp, "missing", "notes.md")).String()
+	a.Editor.editor.SetText("# Failing")
+	a.CurrentFile.dirtySince = time.Now().Add(-time.Minute)
+	a.autosaveAll()
+	assert.True(t, a.CurrentFile.isDirty)
+	assert.True(t, a.CurrentFile.autosaveErr)
+	assert.WithinDuration(t, time.Now(), a.CurrentFile.dirtySince, time.Second)
+}
+
+// Truncated files downloaded in the background are stored to their gist by the editor
+func Test_storeDownloads(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	g := github.Gist{ID: "abc123", Files: []github.File{{Filename: "notes.md", Content: "# No", Truncated: true}, {Filename: "todo.md", Content: "- one"}}}
+	a.openTab(&GistFile{Gist: &g, isOpen: true})
+	a.Editor.activeFile = "todo.md"
+	a.Editor.gist = &g
+
+	other := github.Gist{ID: "def456", Files: []github.File{{Filename: "notes.md", Truncated: true}}}
+	a.Editor.downloads.Add(&other, github.File{Filename: "notes.md", Content: "# Other"})
+	a.Editor.downloads.Add(&g, github.File{Filename: "notes.md", Content: "# Notes", Size: 7})
+	a.Editor.SyncContent()
+	assert.Equal(t, github.File{Filename: "notes.md", Content: "# Notes", Size: 7}, *g.File("notes.md"))
+	assert.True(t, other.Files[0].Truncated, "downloads of gists no longer open should be dropped")
+	assert.Empty(t, a.Editor.downloads.Take())
+}
+
+// The content of a saved gist that round-trips unchanged is kept in the editor,
+// with its undo history and any edits made while saving
+func Test_gistSaved(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	g := github.Gist{}.New("notes.md", "# Notes")
+	g.ID = "abc123"
+	a.openTab(&GistFile{Gist: &g, isOpen: true})
+	a.Editor.SetGist(&g, true)
+	a.Editor.editor.SetText("# Edited")
+	a.Editor.SyncContent()
+	sent := gistSnapshot(a.CurrentFile.Gist)
+	a.Editor.editor.SetText("# Edited more")
+
+	saved := gistSnapshot(&sent)
+	a.gistSaved(a.CurrentFile, sent, saved)
+	assert.Equal(t, "# Edited more", a.Editor.Content(), "edits made while saving should be kept")
+	assert.True(t, a.CurrentFile.isDirty)
+	a.Editor.Undo()
+	assert.NotEqual(t, "# Edited more", a.Editor.Content(), "the undo history should be kept")
+	a.Editor.Redo()
+
+	// Content changed by Github is reloaded
+	a.Editor.SyncContent()
+	sent = gistSnapshot(a.CurrentFile.Gist)
+	saved = gistSnapshot(&sent)
+	saved.Files[0].Content = "# Normalized"
+	a.gistSaved(a.CurrentFile, sent, saved)
+	assert.Equal(t, "# Normalized", a.Editor.Content())
+	assert.False(t, a.CurrentFile.isDirty)
+}
+
+func Test_RecoveryJournal(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	j, err := a.recoveryJournal()
+	require.Nil(t, err)
+	fp := filepath.Join(t.TempDir(), "notes.md")
+	require.Nil(t, os.WriteFile(fp, []byte("# Notes"), 0644))
+
+	g := github.Gist{}.New("notes.md", "# Notes")
+	a.openTab(&GistFile{Gist: &g, isLocal: true, isOpen: true, localURI: storage.NewFileURI(fp).String()})
+	a.Editor.SetGist(&g, false)
+	a.Editor.SetTitle("notes.md")
+	a.pending.Write()
+	entries, _ := j.List()
+	assert.Len(t, entries, 0, "documents without changes are not journaled")
+
+	// Changes are queued in the editor, and written in the background
+	a.Editor.editor.SetText("# Edited")
+	entries, _ = j.List()
+	assert.Len(t, entries, 0)
+	a.pending.Write()
+	entries, _ = j.List()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "# Edited", entries[0].Files[0].Content)
+	assert.Equal(t, "# Notes", g.Files[0].Content, "journaling should not change the open gist")
+	text, err := a.recoveryDiff(entries[0])
+	require.Nil(t, err)
+	assert.Contains(t, text, "+# Edited")
+
+	// Restoring opens the document with its unsaved changes, in its tab if reopened from the last session
+	a.closeFile()
+	require.Nil(t, j.Write(entries[0]))
+	a.showLocalFile(storage.NewFileURI(fp), []byte("# Notes"))
+	reopened := a.EditWindow.Active()
+	tabs := len(a.EditWindow.Tabs())
+	require.Nil(t, a.restoreEntry(entries[0]))
+	assert.Equal(t, reopened, a.EditWindow.Active())
+	assert.Len(t, a.EditWindow.Tabs(), tabs)
+	assert.True(t, a.CurrentFile.isDirty)
+	assert.Equal(t, "# Edited", a.Editor.Content())
+	assert.NotNil(t, a.restoreEntry(entries[0]), "restoring over unsaved changes should fail")
+
+	// Saving removes the entry
+	a.SaveFile()
+	data, _ := os.ReadFile(fp)
+	assert.Equal(t, "# Edited", string(data))
+	entries, _ = j.List()
+	assert.Len(t, entries, 0)
+}
+
+func Test_FileChangedOnDisk(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	fp := filepath.Join(t.TempDir(), "notes.md")
+	require.Nil(t, os.WriteFile(fp, []byte("# Notes\n\none\n"), 0644))
+
+	g := github.Gist{}.New("notes.md", "# Notes\n\none\n")
+	a.openTab(&GistFile{Gist: &g, isLocal: true, isOpen: true, localURI: storage.NewFileURI(fp).String(), diskContent: "# Notes\n\none\n"})
+	a.Editor.SetGist(&g, false)
+	a.watchFiles()
+	defer a.closeFile()
+
+	// Without local edits, the file is reloaded when the change arrives
+	require.Nil(t, os.WriteFile(fp, []byte("# Notes\n\none\ntwo\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return a.Editor.Content() == "# Notes\n\none\ntwo\n"
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, a.CurrentFile.isDirty)
+
+	// Saving is not an external change
+	a.Editor.editor.SetText("# My notes\n\none\ntwo\n")
+	a.SaveFile()
+	a.fileChanged(watchedPath(a.CurrentFile))
+	assert.Nil(t, a.fileChangedDialog)
+
+	// With local edits, the user chooses, and the changes can be merged.
+	// Asking doesn't change the active tab.
+	a.Editor.editor.SetText("# My notes\n\none\ntwo\nthree\n")
+	notes := a.EditWindow.Active()
+	other := github.Gist{}.New("other.md", "")
+	a.openTab(&GistFile{Gist: &other, isOpen: true})
+	require.Nil(t, os.WriteFile(fp, []byte("# Our notes\n\none\ntwo\n"), 0644))
+	assert.Eventually(t, func() bool {
+		a.checkChangedFiles()
+		return a.fileChangedDialog != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.NotEqual(t, notes, a.EditWindow.Active())
+	assert.Equal(t, "# My notes\n\none\ntwo\nthree\n", notes.Editor.Content(), "local edits should be kept until the user chooses")
+	merged, conflicts := mergeFile(notes, "# Our notes\n\none\ntwo\n")
+	assert.False(t, conflicts)
+	assert.Equal(t, "# Our notes\n\none\ntwo\nthree\n", merged)
+
+	// Saving while the user is asked waits for the user's choice
+	saved := false
+	a.EditWindow.Select(notes)
+	a.saveLocalFile(func() { saved = true })
+	assert.False(t, saved)
+	data, err := os.ReadFile(fp)
+	require.Nil(t, err)
+	assert.Equal(t, "# Our notes\n\none\ntwo\n", string(data))
+	a.fileChangedDialog.Hide()
+	assert.True(t, saved)
+	assert.Nil(t, a.fileChangedDialog)
+	data, err = os.ReadFile(fp)
+	require.Nil(t, err)
+	assert.Equal(t, "# My notes\n\none\ntwo\nthree\n", string(data))
+}
+
+func Test_RecentAndSession(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	require.Nil(t, a.LoadConfig())
+	prefs := config.DefaultPreferences()
+	prefs.RestoreSession = true
+	require.Nil(t, a.SavePreferences(prefs))
+	defer func() { require.Nil(t, a.SavePreferences(config.DefaultPreferences())) }()
+
+	fp := filepath.Join(t.TempDir(), "notes.md")
+	require.Nil(t, os.WriteFile(fp, []byte("# Notes\n\none\ntwo"), 0644))
+	uri := storage.NewFileURI(fp)
+	a.showLocalFile(uri, []byte("# Notes\n\none\ntwo"))
+	defer a.closeFile()
+	recent := a.sessionState().Recent
+	require.NotEmpty(t, recent)
+	assert.Equal(t, uri.String(), recent[0].LocalURI, "opened files should be recent")
+
+	a.Editor.SetCursor(2, 99)
+	row, column := a.Editor.Cursor()
+	assert.Equal(t, 2, row)
+	assert.Equal(t, 3, column, "the cursor should be limited to the line")
+	a.Editor.previewEditContainer.SetPreview(true, 0.4)
+	a.saveOpenDocuments()
+
+	// The next launch reopens the file, with its cursor and preview
+	b := AppConfig{}.New()
+	b.MakeUI()
+	require.Nil(t, b.LoadConfig())
+	b.RestoreSession()
+	defer b.closeFile()
+	assert.Equal(t, uri.String(), b.CurrentFile.localURI)
+	assert.Equal(t, "# Notes\n\none\ntwo", b.Editor.Content())
+	row, column = b.Editor.Cursor()
+	assert.Equal(t, []int{2, 3}, []int{row, column})
+	assert.True(t, b.Editor.previewEditContainer.PreviewIsVisible())
+
+	// Missing files are removed from the recent documents
+	require.Nil(t, os.Remove(fp))
+	assert.NotNil(t, b.openDocument(recent[0]))
+	for _, d := range b.sessionState().Recent {
+		assert.NotEqual(t, uri.String(), d.LocalURI)
+	}
+}
+
+func Test_Tabs(t *testing.T) {
+	a := AppConfig{}.New()
+	a.MakeUI()
+	dir := t.TempDir()
+	open := func(name string, content string) fyne.URI {
+		fp := filepath.Join(dir, name)
+		require.Nil(t, os.WriteFile(fp, []byte(content), 0644))
+		uri := storage.NewFileURI(fp)
+		a.showLocalFile(uri, []byte(content))
+		return uri
+	}
+	notes := open("notes.md", "# Notes")
+	first := a.EditWindow.Active()
+	open("todo.md", "# Todo")
+	require.Len(t, a.EditWindow.Tabs(), 2, "each document should have its own tab")
+	second := a.EditWindow.Active()
+	assert.NotEqual(t, first.Editor, second.Editor)
+
+	// Changes and undo history belong to the tab
+	a.Editor.editor.SetText("# Todo today")
+	assert.True(t, second.File.isDirty)
+	assert.False(t, first.File.isDirty)
+	assert.Equal(t, "*todo.md", second.Editor.tab.Text)
+	assert.False(t, first.Editor.editor.CanUndo())
+	assert.True(t, second.Editor.editor.CanUndo())
+
+	// Opening an open document shows its tab
+	a.showLocalFile(notes, []byte("# Notes"))
+	assert.Len(t, a.EditWindow.Tabs(), 2)
+	assert.Equal(t, first, a.EditWindow.Active())
+	assert.Equal(t, first.File, a.CurrentFile)
+	assert.Equal(t, "notes.md - Edit Gist", a.EditWindow.window.Title())
+
+	// Save and close apply to the active tab
+	a.Editor.editor.SetText("# My notes")
+	a.SaveFile()
+	data, _ := os.ReadFile(filepath.Join(dir, "notes.md"))
+	assert.Equal(t, "# My notes", string(data))
+	assert.True(t, second.File.isDirty, "other tabs should not be saved")
+	a.CloseFile()
+	require.Len(t, a.EditWindow.Tabs(), 1)
+	assert.Equal(t, second.File, a.CurrentFile)
+
+	// Closing the last document asks about unsaved changes
+	a.CloseAllFiles()
+	assert.True(t, a.CurrentFile.isOpen)
+	a.closeAllFiles()
+	assert.False(t, a.CurrentFile.isOpen)
+	assert.Len(t, a.EditWindow.Tabs(), 1, "an empty tab is kept for the next document")
+}
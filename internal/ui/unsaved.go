@@ -0,0 +1,62 @@
+// Protection against losing unsaved changes
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// confirmDiscard calls fn, which would replace or close the open document, once
+// its unsaved changes have been dealt with. If there are unsaved changes, the user
+// chooses to save them first, discard them, or cancel.
+func (cfg *AppConfig) confirmDiscard(fn func()) {
+	f := cfg.CurrentFile
+	if !f.isOpen || !f.isDirty {
+		fn()
+		return
+	}
+	cfg.ShowEditWindow()
+	var d *dialog.CustomDialog
+	save := widget.NewButton("Save", func() {
+		d.Hide()
+		cfg.saveFileThen(fn)
+	})
+	save.Importance = widget.HighImportance
+	discard := widget.NewButton("Discard", func() {
+		d.Hide()
+		cfg.discardJournal(cfg.CurrentFile)
+		fn()
+	})
+	cancel := widget.NewButton("Cancel", func() { d.Hide() })
+	msg := widget.NewLabel(fmt.Sprintf("Save the changes to %s?\nYour changes will be lost if you don't save them.", cfg.Editor.Title))
+	d = dialog.NewCustomWithoutButtons("Unsaved changes", container.NewVBox(msg), cfg.Editor.editWindow)
+	d.SetButtons([]fyne.CanvasObject{cancel, discard, save})
+	d.Show()
+}
+
+// confirmDiscardAll calls fn, which would close every open document, once the
+// unsaved changes of each document have been dealt with, one tab at a time.
+// Cancelling for any document cancels fn.
+func (cfg *AppConfig) confirmDiscardAll(fn func()) {
+	cfg.confirmDiscardTabs(cfg.EditWindow.Tabs(), fn)
+}
+
+// confirmDiscardTabs deals with the unsaved changes of the first tab, then the rest, then calls fn
+func (cfg *AppConfig) confirmDiscardTabs(tabs []*Tab, fn func()) {
+	if len(tabs) == 0 {
+		fn()
+		return
+	}
+	t := tabs[0]
+	next := func() { cfg.confirmDiscardTabs(tabs[1:], fn) }
+	if !t.File.isOpen || !t.File.isDirty {
+		next()
+		return
+	}
+	cfg.EditWindow.Select(t)
+	cfg.confirmDiscard(next)
+}
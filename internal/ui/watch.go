@@ -0,0 +1,388 @@
+// Detection of changes made to open local files by other programs, such as a git pull
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fieldse/gist-editor/internal/diff"
+	"github.com/fieldse/gist-editor/internal/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long to wait for a file's changes to settle before reading it.
+// Programs often write a file in several steps.
+var watchDelay = 200 * time.Millisecond
+
+// fileWatcher watches files for changes. Changes are passed to onChange when they
+// arrive, and the changes it doesn't handle are recorded until they are taken with Changed.
+// Their directories are watched, rather than the files, so that files replaced by a rename
+// are still followed.
+type fileWatcher struct {
+	watcher  *fsnotify.Watcher
+	mu       sync.Mutex
+	paths    map[string]*time.Timer // the watched files, with timers to delay recording changes until they settle
+	dirs     map[string]int         // the watched directories, with their number of watched files
+	changed  map[string]bool        // the watched files changed since the last call to Changed
+	onChange func(path string) bool // handles a change when it arrives; returns false to record it
+}
+
+// newFileWatcher returns a new fileWatcher, passing changes to onChange, if not nil, when they arrive
+func newFileWatcher(onChange func(path string) bool) (*fileWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	fw := &fileWatcher{watcher: w, paths: map[string]*time.Timer{}, dirs: map[string]int{}, changed: map[string]bool{}, onChange: onChange}
+	go fw.run()
+	return fw, nil
+}
+
+// Changed returns the paths of the watched files changed since the last call
+func (fw *fileWatcher) Changed() []string {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	var res []string
+	for p := range fw.changed {
+		res = append(res, p)
+	}
+	sort.Strings(res)
+	fw.changed = map[string]bool{}
+	return res
+}
+
+// Watch watches the files at the paths, instead of any previously watched files
+func (fw *fileWatcher) Watch(paths []string) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	keep := map[string]bool{}
+	for _, p := range paths {
+		keep[p] = true
+	}
+	for p := range fw.paths {
+		if !keep[p] {
+			fw.unwatch(p)
+		}
+	}
+	var res error
+	for p := range keep {
+		if _, ok := fw.paths[p]; ok {
+			continue
+		}
+		dir := filepath.Dir(p)
+		if fw.dirs[dir] == 0 {
+			if err := fw.watcher.Add(dir); err != nil {
+				res = err
+				continue
+			}
+		}
+		fw.dirs[dir]++
+		fw.paths[p] = nil
+		logger.Debug("watching %s for changes", p)
+	}
+	return res
+}
+
+// unwatch stops watching a file, and its directory if no other file in it is watched.
+// The lock must be held.
+func (fw *fileWatcher) unwatch(path string) {
+	if timer := fw.paths[path]; timer != nil {
+		timer.Stop()
+	}
+	delete(fw.paths, path)
+	delete(fw.changed, path)
+	dir := filepath.Dir(path)
+	fw.dirs[dir]--
+	if fw.dirs[dir] > 0 {
+		return
+	}
+	delete(fw.dirs, dir)
+	if err := fw.watcher.Remove(dir); err != nil {
+		logger.Error("stop watching file failed", err)
+	}
+}
+
+// run handles the watcher's events, until the watcher is closed
+func (fw *fileWatcher) run() {
+	for {
+		select {
+		case event, ok := <-fw.watcher.Events:
+			if !ok {
+				return
+			}
+			fw.handle(event)
+		case err, ok := <-fw.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Error("watch file failed", err)
+		}
+	}
+}
+
+// handle passes a change to a watched file to onChange once its changes have settled,
+// and records it if not handled
+func (fw *fileWatcher) handle(event fsnotify.Event) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	path := filepath.Clean(event.Name)
+	timer, watched := fw.paths[path]
+	if !watched || event.Op == fsnotify.Chmod {
+		return
+	}
+	if timer != nil {
+		timer.Stop()
+	}
+	fw.paths[path] = time.AfterFunc(watchDelay, func() {
+		if fw.onChange != nil && fw.onChange(path) {
+			return
+		}
+		fw.mu.Lock()
+		defer fw.mu.Unlock()
+		if _, watched := fw.paths[path]; watched {
+			fw.changed[path] = true
+		}
+	})
+}
+
+// watchedPath returns the path on disk of a document, if it is a saved local file.
+// Symlinks are resolved, as saving replaces the file they point to.
+func watchedPath(f *GistFile) string {
+	if !f.isOpen || !f.isLocal || f.localURI == "" {
+		return ""
+	}
+	uri, err := storage.ParseURI(f.localURI)
+	if err != nil || uri.Scheme() != "file" {
+		return ""
+	}
+	path := filepath.Clean(uri.Path())
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
+}
+
+// watchFiles watches the open local files for changes made by other programs,
+// and stops watching files that are no longer open
+func (cfg *AppConfig) watchFiles() {
+	var paths []string
+	for _, t := range cfg.EditWindow.Tabs() {
+		if p := watchedPath(t.File); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if cfg.watcher == nil {
+		if len(paths) == 0 {
+			return
+		}
+		w, err := newFileWatcher(cfg.reloadChangedFile)
+		if err != nil {
+			logger.Error("create file watcher failed", err)
+			return
+		}
+		cfg.watcher = w
+	}
+	if err := cfg.watcher.Watch(paths); err != nil {
+		logger.Error("watch file failed", err)
+	}
+}
+
+// checkChangedFiles handles the changes made on disk to open local files with unsaved
+// changes, recorded by the watcher since the last check. It runs where the documents are
+// changed: when the app comes to the foreground, a tab is selected, or a file is saved.
+// Files changed while the user is asked about another file are handled once the user has chosen.
+func (cfg *AppConfig) checkChangedFiles() {
+	if cfg.watcher == nil {
+		return
+	}
+	for _, p := range cfg.watcher.Changed() {
+		if !contains(cfg.changedFiles, p) {
+			cfg.changedFiles = append(cfg.changedFiles, p)
+		}
+	}
+	for len(cfg.changedFiles) > 0 && cfg.fileChangedDialog == nil {
+		p := cfg.changedFiles[0]
+		cfg.changedFiles = cfg.changedFiles[1:]
+		cfg.fileChanged(p)
+	}
+}
+
+// contains returns true if the list holds s
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// changedOnDisk returns the tab of the open local file at the path, and its content on disk,
+// if the file was changed by another program since it was last loaded or saved
+func (cfg *AppConfig) changedOnDisk(path string) (*Tab, string, bool) {
+	var t *Tab
+	for _, x := range cfg.EditWindow.Tabs() {
+		if watchedPath(x.File) == path {
+			t = x
+		}
+	}
+	if t == nil {
+		return nil, "", false
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Warn("open file %s was removed", path)
+		return nil, "", false
+	}
+	if err != nil {
+		logger.Error("read changed file failed", err)
+		return nil, "", false
+	}
+	disk := string(data)
+	if disk == t.File.diskContent {
+		return nil, "", false // saved by the app, or not changed
+	}
+	return t, disk, true
+}
+
+// reloadChangedFile reloads an open local file changed on disk without unsaved changes,
+// when the watcher reports the change. Returns false if the file has unsaved changes,
+// so that the user is asked what to do once the change is checked.
+func (cfg *AppConfig) reloadChangedFile(path string) bool {
+	t, disk, changed := cfg.changedOnDisk(path)
+	if !changed {
+		return true
+	}
+	if t.File.isDirty {
+		return false
+	}
+	logger.Info("reloading %s, which was changed on disk", path)
+	cfg.reloadFile(t, disk)
+	return true
+}
+
+// fileChanged handles a change on disk to an open local file. Without unsaved changes
+// the file is reloaded; otherwise the user chooses to reload, keep their version, or merge.
+func (cfg *AppConfig) fileChanged(path string) {
+	t, disk, changed := cfg.changedOnDisk(path)
+	if !changed {
+		return
+	}
+	if !t.File.isDirty {
+		logger.Info("reloading %s, which was changed on disk", path)
+		cfg.reloadFile(t, disk)
+		return
+	}
+	logger.Info("%s was changed on disk while it has unsaved changes", path)
+	cfg.showFileChanged(t, disk)
+}
+
+// reloadFile replaces the content of the tab's local file with its content on disk
+func (cfg *AppConfig) reloadFile(t *Tab, disk string) {
+	f := t.File
+	if len(f.Gist.Files) > 0 {
+		f.Gist.Files[0].Content = disk
+	}
+	f.diskContent = disk
+	t.Editor.ReloadGist(f.Gist)
+	cfg.setFileDirty(f, t.Editor, false)
+}
+
+// showFileChanged asks the user what to do with the tab's file, which has unsaved
+// changes and was changed on disk. The tab is made active once the user chooses to
+// reload or merge.
+func (cfg *AppConfig) showFileChanged(t *Tab, disk string) {
+	if cfg.fileChangedDialog != nil {
+		cfg.fileChangedDialog.Hide()
+	}
+	var d *dialog.CustomDialog
+	reload := widget.NewButton("Reload", func() {
+		d.Hide()
+		cfg.EditWindow.Select(t)
+		cfg.reloadFile(t, disk)
+	})
+	keep := widget.NewButton("Keep mine", func() {
+		d.Hide()
+		t.File.diskContent = disk // saving replaces the version on disk
+	})
+	merging := false
+	merge := widget.NewButton("Merge...", func() {
+		merging = true // the user is still choosing, in the merge dialog
+		d.Hide()
+		cfg.EditWindow.Select(t)
+		cfg.showMerge(t, disk)
+	})
+	merge.Importance = widget.HighImportance
+	msg := widget.NewLabel(fmt.Sprintf("%s was changed by another program, and you have unsaved changes.\nReload it from disk, losing your changes, keep your version, or merge both?", t.Editor.Title))
+	d = dialog.NewCustomWithoutButtons("File changed on disk", container.NewVBox(msg), t.Editor.editWindow)
+	d.SetButtons([]fyne.CanvasObject{reload, keep, merge})
+	d.SetOnClosed(func() {
+		if !merging {
+			cfg.fileChangedClosed()
+		}
+	})
+	cfg.fileChangedDialog = d
+	cfg.ShowEditWindow()
+	d.Show()
+}
+
+// mergeFile returns the merge of the tab's unsaved changes and the changes made on disk,
+// both made to the content last loaded or saved
+func mergeFile(t *Tab, disk string) (string, bool) {
+	return diff.Merge(t.File.diskContent, t.Editor.Content(), disk, "mine", "on disk")
+}
+
+// showMerge shows the merge of the user's changes and the changes on disk, to be
+// edited and used in place of the editor content
+func (cfg *AppConfig) showMerge(t *Tab, disk string) {
+	merged, conflicts := mergeFile(t, disk)
+	text := "Your changes and the changes on disk were merged. Review the result before using it."
+	if conflicts {
+		text = fmt.Sprintf("Some changes conflict. Both versions are kept between %q and %q lines: edit them before using the result.",
+			diff.ConflictStart, diff.ConflictEnd)
+	}
+	msg := widget.NewLabel(text)
+	msg.Wrapping = fyne.TextWrapWord
+	entry := widget.NewMultiLineEntry()
+	entry.SetText(merged)
+	d := dialog.NewCustomConfirm("Merge changes", "Use merged", "Cancel", container.NewBorder(msg, nil, nil, nil, entry), func(ok bool) {
+		cfg.fileChangedDialog = nil
+		cfg.EditWindow.Select(t)
+		if !ok {
+			cfg.showFileChanged(t, disk)
+			return
+		}
+		t.File.diskContent = disk
+		t.Editor.SetContent(entry.Text)
+		cfg.setDirty(true)
+		cfg.fileChangedClosed()
+	}, t.Editor.editWindow)
+	d.Resize(fyne.NewSize(700, 500))
+	cfg.fileChangedDialog = d
+	d.Show()
+}
+
+// fileChangedClosed handles the files changed while the user was asked about a change on
+// disk, then the saves waiting for the user's choice
+func (cfg *AppConfig) fileChangedClosed() {
+	cfg.fileChangedDialog = nil
+	cfg.checkChangedFiles()
+	if cfg.fileChangedDialog != nil {
+		return
+	}
+	saves := cfg.pendingSaves
+	cfg.pendingSaves = nil
+	for _, save := range saves {
+		save()
+	}
+}